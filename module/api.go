@@ -27,12 +27,12 @@ func NewCmdApi() *cobra.Command {
 		Run: func(cmd *cobra.Command, args []string) {
 			header := []string{"Method", "Api", "Description"}
 			if verbose {
-				header = append(header, "Link")
+				header = append(header, "MediaType", "Link")
 			}
 			PrityPrint(header, filterApi(args, verbose))
 		},
 	}
-	cmd.Flags().BoolVarP(&verbose, "verbose", "v", false, "show link to document")
+	cmd.Flags().BoolVarP(&verbose, "verbose", "v", false, "show media type of request and link to document")
 
 	return cmd
 }
@@ -50,7 +50,7 @@ func filterApi(filter []string, verbose bool) [][]string {
 		if matched {
 			row := []string{v[0], v[1], v[2]}
 			if verbose {
-				row = append(row, v[4])
+				row = append(row, apiMediaTypes[v[0]+" "+v[1]], v[4])
 			}
 			data = append(data, row)
 		}
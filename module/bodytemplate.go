@@ -0,0 +1,100 @@
+package module
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"strings"
+	"text/template"
+
+	"gopkg.in/yaml.v2"
+)
+
+// BodyTemplate renders a request body as a text/template with the variables
+// of --values and --set, e.g. <Name>{{.name}}</Name>.
+type BodyTemplate struct {
+	// "key=value"
+	Set []string
+	// yaml file of the variables, overridden by Set
+	ValuesFile string
+}
+
+// Enabled reports whether the body is a template: variables are given, or
+// the file is a .tmpl.
+func (t *BodyTemplate) Enabled(fileName string) bool {
+	return len(t.Set) > 0 || t.ValuesFile != "" || strings.HasSuffix(fileName, ".tmpl")
+}
+
+func (t *BodyTemplate) values() (map[string]any, error) {
+	values := map[string]any{}
+	if t.ValuesFile != "" {
+		data, err := os.ReadFile(t.ValuesFile)
+		if err != nil {
+			return nil, err
+		}
+		if err := yaml.Unmarshal(data, &values); err != nil {
+			return nil, fmt.Errorf("%s: %w", t.ValuesFile, err)
+		}
+	}
+	for _, kv := range t.Set {
+		key, value, ok := strings.Cut(kv, "=")
+		if !ok || key == "" {
+			return nil, fmt.Errorf("invalid --set \"%s\", expected key=value", kv)
+		}
+		values[key] = value
+	}
+	return values, nil
+}
+
+// Render executes the body as a template. A variable missing from the
+// values is an error, rather than "<no value>" sent to vCD.
+func (t *BodyTemplate) Render(name string, body []byte) ([]byte, error) {
+	values, err := t.values()
+	if err != nil {
+		return nil, err
+	}
+	tmpl, err := template.New(name).Funcs(lookupFuncs()).Option("missingkey=error").Parse(string(body))
+	if err != nil {
+		return nil, err
+	}
+	var out bytes.Buffer
+	if err := tmpl.Execute(&out, values); err != nil {
+		return nil, err
+	}
+	return out.Bytes(), nil
+}
+
+// lookupFuncs are the template functions resolving objects by name with
+// ResolveRef, e.g. {{urn "vdc" .vdc}} or {{(ref "vapp" "web01").Href}}.
+// The objects are looked up once per template.
+func lookupFuncs() template.FuncMap {
+	refs := map[string]ObjectRef{}
+	ref := func(kind string, name string) (ObjectRef, error) {
+		key := kind + ":" + name
+		if r, ok := refs[key]; ok {
+			return r, nil
+		}
+		r, err := ResolveRef(kind, name)
+		if err != nil {
+			return ObjectRef{}, err
+		}
+		refs[key] = r
+		return r, nil
+	}
+	form := func(form string) func(string, string) (string, error) {
+		return func(kind string, name string) (string, error) {
+			r, err := ref(kind, name)
+			if err != nil {
+				return "", err
+			}
+			return r.Get(form)
+		}
+	}
+	return template.FuncMap{
+		"ref":  ref,
+		"urn":  form("urn"),
+		"uuid": form("uuid"),
+		"id":   form("id"),
+		"href": form("href"),
+	}
+}
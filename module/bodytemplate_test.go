@@ -0,0 +1,92 @@
+package module
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestBodyTemplateEnabled(t *testing.T) {
+	tests := []struct {
+		tmpl     BodyTemplate
+		fileName string
+		want     bool
+	}{
+		{BodyTemplate{}, "", false},
+		{BodyTemplate{}, "vapp.xml", false},
+		{BodyTemplate{}, "vapp.xml.tmpl", true},
+		{BodyTemplate{}, "tmpl", false},
+		{BodyTemplate{Set: []string{"name=web01"}}, "", true},
+		{BodyTemplate{ValuesFile: "values.yaml"}, "vapp.xml", true},
+	}
+	for _, tt := range tests {
+		if got := tt.tmpl.Enabled(tt.fileName); got != tt.want {
+			t.Errorf("Enabled(%q) of %+v = %v, want %v", tt.fileName, tt.tmpl, got, tt.want)
+		}
+	}
+}
+
+func TestBodyTemplateRender(t *testing.T) {
+	valuesFile := filepath.Join(t.TempDir(), "values.yaml")
+	if err := os.WriteFile(valuesFile, []byte("name: web01\ncount: 2\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		tmpl BodyTemplate
+		body string
+		want string
+		err  string
+	}{
+		{BodyTemplate{ValuesFile: valuesFile}, "<Name>{{.name}}</Name><Count>{{.count}}</Count>", "<Name>web01</Name><Count>2</Count>", ""},
+		// --set overrides --values
+		{BodyTemplate{ValuesFile: valuesFile, Set: []string{"name=web02"}}, "<Name>{{.name}}</Name><Count>{{.count}}</Count>", "<Name>web02</Name><Count>2</Count>", ""},
+		{BodyTemplate{Set: []string{"name=a=b"}}, "{{.name}}", "a=b", ""},
+		{BodyTemplate{Set: []string{"name=web01"}}, "<Name>{{.name}}</Name><Description>{{.description}}</Description>", "", `map has no entry for key "description"`},
+		{BodyTemplate{Set: []string{"name"}}, "{{.name}}", "", `invalid --set "name"`},
+		{BodyTemplate{Set: []string{"=web01"}}, "{{.name}}", "", `invalid --set "=web01"`},
+		{BodyTemplate{ValuesFile: valuesFile + ".none"}, "{{.name}}", "", "no such file"},
+		{BodyTemplate{Set: []string{"name=web01"}}, "{{.name", "", "unclosed action"},
+	}
+	for _, tt := range tests {
+		got, err := tt.tmpl.Render("body", []byte(tt.body))
+		if tt.err != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.err) {
+				t.Errorf("Render(%q) of %+v = %q, %v, want error %q", tt.body, tt.tmpl, got, err, tt.err)
+			}
+			continue
+		}
+		if err != nil || string(got) != tt.want {
+			t.Errorf("Render(%q) of %+v = %q, %v, want %q", tt.body, tt.tmpl, got, err, tt.want)
+		}
+	}
+}
+
+func TestBodyTemplateLookup(t *testing.T) {
+	connectReplay(t, "resolve")
+	tests := []struct {
+		body string
+		want string
+		err  string
+	}{
+		{`{{urn "vdc" .vdc}}`, "urn:vcloud:vdc:5e6f7a8b-0000-4000-8000-0000000000a2", ""},
+		{`{{id "vapp" "web01"}} {{uuid "vapp" "web01"}}`, "vapp-3c4d5e6f-0000-4000-8000-000000000001 3c4d5e6f-0000-4000-8000-000000000001", ""},
+		{`{{href "vapp" (printf "%s/web02" .vdc)}}`, "https://vcd.example.com/api/vApp/vapp-3c4d5e6f-0000-4000-8000-000000000003", ""},
+		{`{{(ref "edge" "vdc-b/edge01").Urn}}`, "urn:vcloud:gateway:7a8b9c0d-0000-4000-8000-000000000001", ""},
+		{`{{href "edge" "vdc-b/edge01"}}`, "", "'edge01' has no href"},
+		{`{{urn "vapp" "web02"}}`, "", "vApp name 'web02' is ambiguous"},
+	}
+	tmpl := BodyTemplate{Set: []string{"vdc=vdc-b"}}
+	for _, tt := range tests {
+		got, err := tmpl.Render("body", []byte(tt.body))
+		if tt.err != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.err) {
+				t.Errorf("Render(%q) = %q, %v, want error %q", tt.body, got, err, tt.err)
+			}
+			continue
+		}
+		if err != nil || string(got) != tt.want {
+			t.Errorf("Render(%q) = %q, %v, want %q", tt.body, got, err, tt.want)
+		}
+	}
+}
@@ -2,82 +2,276 @@ package module
 
 import (
 	"bytes"
-	"crypto/tls"
+	"encoding/json"
 	"fmt"
 	"io"
 	"net/http"
-	"strings"
+	"net/url"
+	"os"
+	"sync"
 	"time"
 )
 
 func initClient() {
-	initConfig()
+	if err := initConfig(); err != nil {
+		Fatal(err)
+	}
+
+	// encrypt the base64 passwords of older versions, only when the passphrase
+	// is given: the completion and the scripts must not wait for a prompt.
+	// config set-site migrates them otherwise.
+	if os.Getenv("VCDCTL_PASSPHRASE") != "" {
+		if migrated, err := config.migratePasswords(); err != nil {
+			Log(fmt.Sprintf("passwords are not migrated: %v", err))
+		} else if migrated {
+			if err := saveConfig(); err != nil {
+				Fatal(err)
+			}
+		}
+	}
 
 	site, err := config.GetCurrentSite()
 	if err != nil {
 		Fatal(err)
 	}
-	client = *newVcdClient(site)
-
-	if err := client.Login(); err != nil {
+	if err := Connect(site); err != nil {
 		Fatal(err)
 	}
 }
 
-func newVcdClient(site Site) *VcdClient {
-	transportConfig := &http.Transport{
-		TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
+// Connect logs in to the site and makes it the client used by the getters.
+// The cached session of the site is reused when there is one.
+func Connect(site Site) error {
+	vcdClient, err := newVcdClient(site)
+	if err != nil {
+		return err
+	}
+	if err := vcdClient.prepareApiVersion(); err != nil {
+		return err
+	}
+	if token := cachedToken(site); token != "" {
+		vcdClient.token = token
+	} else if err := vcdClient.Login(); err != nil {
+		return err
+	}
+	cacheToken(site, vcdClient.token)
+	client = *vcdClient
+	return nil
+}
+
+func newVcdClient(site Site) (*VcdClient, error) {
+	tlsConfig, err := newTLSConfig(site)
+	if err != nil {
+		return nil, err
+	}
+	transport, err := wrapTransport(&http.Transport{
+		TLSClientConfig: tlsConfig,
+	})
+	if err != nil {
+		return nil, err
+	}
+	timeout, err := siteTimeout(site)
+	if err != nil {
+		return nil, fmt.Errorf("invalid timeout of site '%s': %w", site.Name, err)
 	}
 	httpClient := &http.Client{
-		Transport: transportConfig,
-		Timeout:   time.Duration(30) * time.Second,
+		Transport: transport,
+		Timeout:   timeout,
 	}
-	vcdClient := &VcdClient{token: "", httpClient: httpClient}
+	vcdClient := &VcdClient{token: "", httpClient: httpClient, tokenMu: &sync.RWMutex{}, loginMu: &sync.Mutex{}}
 	vcdClient.site = site
-	return vcdClient
+	vcdClient.retry = defaultRetryPolicy()
+	vcdClient.limiter = newRateLimiter(rateLimit)
+	return vcdClient, nil
 }
 
 type VcdClient struct {
 	token      string
 	site       Site
 	httpClient *http.Client
+	retry      RetryPolicy
+	limiter    *rateLimiter
+	// pointers, as the client is copied to the global client
+	tokenMu *sync.RWMutex
+	loginMu *sync.Mutex
 }
 
 type Response struct {
 	*http.Response
 	Header map[string][]string
 	Body   []byte
-	Error  error
 }
 
+// Login gets a new session token. The token in use is replaced only when the
+// login succeeds: concurrent requests keep sending it meanwhile.
 func (c *VcdClient) Login() error {
-	header := map[string]string{"Authorization": "Basic " + c.site.GetCredential()}
-	org := strings.Split(c.site.User, "@")[1]
+	if replayDir != "" {
+		// the recorded responses need no credential
+		c.setToken(replayToken)
+		return nil
+	}
+	if c.site.UsesApiToken() {
+		return c.loginWithApiToken()
+	}
+
+	credential, err := c.site.GetCredential()
+	if err != nil {
+		return err
+	}
+	header := map[string]string{"Authorization": "Basic " + credential}
+	_, org, err := c.site.LoginUser()
+	if err != nil {
+		return err
+	}
 	login_url := "/cloudapi/1.0.0/sessions"
-	if org == "system" {
+	if isSystemOrg(org) {
 		login_url = "/cloudapi/1.0.0/sessions/provider"
 	}
-	res := c.Request("POST", login_url, header, nil)
-	if token, ok := res.Header["X-Vmware-Vcloud-Access-Token"]; ok {
-		c.token = token[0]
-	} else {
-		Fatal(res.Header, res.Body)
+	// the Authorization header replaces the session token
+	res, err := c.Request("POST", login_url, header, nil)
+	if err != nil {
+		return err
+	}
+	token, ok := res.Header["X-Vmware-Vcloud-Access-Token"]
+	if !ok {
+		return fmt.Errorf("login to %s failed: no access token in response", c.site.Endpoint)
+	}
+	c.setToken(token[0])
+	return nil
+}
+
+// loginWithApiToken exchanges the api token (an OAuth refresh token) for an
+// access token.
+func (c *VcdClient) loginWithApiToken() error {
+	apiToken, err := c.site.GetApiToken()
+	if err != nil {
+		return err
+	}
+	tokenUrl := "/oauth/tenant/" + url.PathEscape(c.site.OrgName) + "/token"
+	if isSystemOrg(c.site.OrgName) {
+		tokenUrl = "/oauth/provider/token"
+	}
+	header := map[string]string{
+		"Accept":       "application/json",
+		"Content-Type": "application/x-www-form-urlencoded",
+	}
+	form := url.Values{"grant_type": {"refresh_token"}, "refresh_token": {apiToken}}
+	// sent without the session token, and without logging in again on a 401
+	res, err := c.request("POST", tokenUrl, header, []byte(form.Encode()))
+	if err != nil {
+		return err
+	}
+	var token struct {
+		AccessToken string `json:"access_token"`
+	}
+	if err := json.Unmarshal(res.Body, &token); err != nil {
+		return responseError(res, err)
+	}
+	if token.AccessToken == "" {
+		return fmt.Errorf("login to %s failed: no access token in response", c.site.Endpoint)
 	}
+	c.setToken(token.AccessToken)
 	return nil
 }
 
-func (c *VcdClient) Request(method string, path string, header map[string]string, req_data []byte) *Response {
+// Logout deletes the session on vCD. An expired session is not renewed
+// just to be deleted.
+func (c *VcdClient) Logout() error {
+	_, err := c.request("DELETE", "/cloudapi/1.0.0/sessions/current", withToken(nil, c.getToken()), nil)
+	return err
+}
+
+// Request sends the request with the session token. When vCD rejects the
+// token (the cached session expired), it logs in again and retries once.
+// It is safe for concurrent use.
+func (c *VcdClient) Request(method string, path string, header map[string]string, req_data []byte) (*Response, error) {
+	token := c.getToken()
+	res, err := c.request(method, path, withToken(header, token), req_data)
+	if res == nil || res.StatusCode != http.StatusUnauthorized {
+		return res, err
+	}
+	if _, ok := header["Authorization"]; ok {
+		// the login itself, or the caller's own credential
+		return res, err
+	}
+
+	// only one of the concurrent requests logs in, the others use its token
+	c.loginMu.Lock()
+	if c.getToken() == token {
+		Log("session expired, logging in again")
+		if err := c.Login(); err != nil {
+			c.loginMu.Unlock()
+			return nil, err
+		}
+		cacheToken(c.site, c.getToken())
+	}
+	c.loginMu.Unlock()
+	return c.request(method, path, withToken(header, c.getToken()), req_data)
+}
+
+// withToken returns the header with the session token, unless it has its
+// own Authorization. The token is read once per request by the caller, so
+// that a relogin in progress does not change it in the middle.
+func withToken(header map[string]string, token string) map[string]string {
+	if token == "" {
+		return header
+	}
+	if _, ok := header["Authorization"]; ok {
+		return header
+	}
+	withToken := map[string]string{"Authorization": "Bearer " + token}
+	for k, v := range header {
+		withToken[k] = v
+	}
+	return withToken
+}
+
+func (c *VcdClient) getToken() string {
+	c.tokenMu.RLock()
+	defer c.tokenMu.RUnlock()
+	return c.token
+}
+
+func (c *VcdClient) setToken(token string) {
+	c.tokenMu.Lock()
+	defer c.tokenMu.Unlock()
+	c.token = token
+}
+
+// request sends the request, again while it fails transiently (see RetryPolicy).
+func (c *VcdClient) request(method string, path string, header map[string]string, req_data []byte) (*Response, error) {
+	for attempt := 0; ; attempt++ {
+		c.limiter.Wait()
+		res, res_body, err := c.send(method, path, header, req_data)
+		if !c.retry.ShouldRetry(method, res, err, attempt) {
+			if err != nil {
+				return nil, err
+			}
+			return c.newResponse(res, res_body)
+		}
+
+		delay := c.retry.Delay(res, attempt)
+		reason := ""
+		if err != nil {
+			reason = err.Error()
+		} else {
+			reason = res.Status
+		}
+		Log(fmt.Sprintf("%s %s: %s, retrying in %s", method, path, reason, delay.Round(time.Millisecond)))
+		time.Sleep(delay)
+	}
+}
+
+// send makes a single attempt of the request.
+func (c *VcdClient) send(method string, path string, header map[string]string, req_data []byte) (*http.Response, []byte, error) {
 	// Make request
-	req, err := http.NewRequest(method, c.site.Endpoint+path, bytes.NewBuffer(req_data))
+	req, err := http.NewRequest(method, c.site.Endpoint+path, bytes.NewReader(req_data))
 	if err != nil {
-		Fatal(err)
+		return nil, nil, err
 	}
 
 	// Add headers
-	req.Header.Set("Accept", "application/*;version=" + c.site.ApiVersion)
-	if c.token != "" {
-		req.Header.Set("Authorization", "Bearer "+c.token)
-	}
+	req.Header.Set("Accept", "application/*;version="+c.site.ApiVersion)
 	for k, v := range header {
 		req.Header.Set(k, v)
 	}
@@ -85,7 +279,10 @@ func (c *VcdClient) Request(method string, path string, header map[string]string
 	if isDebugMode {
 		fmt.Printf("Method: %s\n", method)
 		fmt.Printf("Path: %s\n", path)
-		for key, value := range(header){
+		for key, value := range header {
+			if key == "Authorization" {
+				value = redacted
+			}
 			fmt.Printf("Header: %s: %s\n", key, value)
 		}
 		fmt.Printf("Data: %s\n", bytes.NewBuffer(req_data))
@@ -97,11 +294,25 @@ func (c *VcdClient) Request(method string, path string, header map[string]string
 		fmt.Println(res)
 	}
 	if err != nil {
-		Fatal(err)
+		return nil, nil, err
 	}
+	defer res.Body.Close()
 	res_body, err := io.ReadAll(res.Body)
 	if err != nil {
-		Fatal(err)
+		return nil, nil, err
+	}
+	return res, res_body, nil
+}
+
+func (c *VcdClient) newResponse(res *http.Response, res_body []byte) (*Response, error) {
+	response := &Response{res, res.Header, res_body}
+	if res.StatusCode < 200 || res.StatusCode >= 300 {
+		// the response is returned as well, for the callers which show the body
+		apiError := newApiError(response)
+		if apiError.StackTrace != "" {
+			Log("StackTrace: " + apiError.StackTrace)
+		}
+		return response, apiError
 	}
-	return &Response{res, res.Header, res_body, nil}
+	return response, nil
 }
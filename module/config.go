@@ -4,9 +4,10 @@ import (
 	"encoding/base64"
 	"encoding/json"
 	"fmt"
-	"log"
 	"os"
 	"runtime"
+	"strings"
+	"time"
 
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
@@ -17,12 +18,23 @@ func NewCmdConfig() *cobra.Command {
 		Use:   "config",
 		Short: "config setting",
 		PersistentPreRun: func(cmd *cobra.Command, args []string) {
-			initConfig()
+			if err := initConfig(); err != nil {
+				Fatal(err)
+			}
 		},
 	}
 	cmd.AddCommand(
 		NewCmdConfigGetSites(),
 		NewCmdConfigSetSite(),
+		NewCmdConfigUseSite(),
+		NewCmdConfigDeleteSite(),
+		NewCmdConfigRenameSite(),
+		NewCmdConfigTestSite(),
+		NewCmdConfigTrustSite(),
+		NewCmdConfigGetContexts(),
+		NewCmdConfigSetContext(),
+		NewCmdConfigUseContext(),
+		NewCmdConfigDeleteContext(),
 	)
 	return cmd
 }
@@ -31,44 +43,336 @@ func NewCmdConfigSetSite() *cobra.Command {
 	var endpoint string
 	var user string
 	var password string
+	var passwordEnv string
+	var passwordCommand string
+	var apiToken string
+	var apiTokenEnv string
+	var apiTokenCommand string
 	var orgname string
+	var insecure bool
+	var caFile string
+	var fingerprint string
+	var timeout time.Duration
 
 	cmd := &cobra.Command{
 		Use:   "set-site ${SITE_NAME}",
-		Short: "add vcd site configuration",
+		Short: "add vcd site configuration, or update the given fields of the existing site",
 		Args:  cobra.ExactArgs(1),
+		ValidArgsFunction: func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+			if len(args) != 0 {
+				return nil, cobra.ShellCompDirectiveNoFileComp
+			}
+			return completeSiteNames()
+		},
 		Run: func(cmd *cobra.Command, args []string) {
 			name := args[0]
+			flags := cmd.Flags()
+
+			passwordSources := countNonEmpty(password, passwordEnv, passwordCommand)
+			tokenSources := countNonEmpty(apiToken, apiTokenEnv, apiTokenCommand)
+			if passwordSources > 1 {
+				Fatal("only one of --password, --password-env and --password-command can be specified")
+			}
+			if tokenSources > 1 {
+				Fatal("only one of --api-token, --api-token-env and --api-token-command can be specified")
+			}
+			if passwordSources > 0 && tokenSources > 0 {
+				Fatal("password and api token can not be specified together")
+			}
 
 			site, err := config.GetSite(name)
-			if err != nil {
-				site = Site{}
-				site.Name = name
+			isNew := err != nil
+			if isNew {
+				if endpoint == "" || orgname == "" {
+					Fatal("--endpoint and --orgname are required for a new site")
+				}
+				if user == "" && tokenSources == 0 {
+					Fatal("--user is required unless an api token is specified")
+				}
+				site = Site{Name: name}
+			}
+
+			if flags.Changed("endpoint") && endpoint != site.Endpoint {
 				site.Endpoint = endpoint
+				// renegotiated with the new endpoint
+				site.ApiVersion = ""
+			}
+			if flags.Changed("user") {
 				site.User = user
-				site.SetPassword(password)
+			}
+			if flags.Changed("orgname") {
 				site.OrgName = orgname
+			}
+			if flags.Changed("insecure") {
+				site.Insecure = insecure
+			}
+			if flags.Changed("ca-file") {
+				site.CaFile = caFile
+			}
+			if flags.Changed("fingerprint") {
+				site.Fingerprint = fingerprint
+			}
+			if flags.Changed("timeout") {
+				site.Timeout = ""
+				if timeout > 0 {
+					site.Timeout = timeout.String()
+				}
+			}
+
+			// a new credential replaces the old one, whatever its source was
+			newCredential := passwordSources > 0 || tokenSources > 0 || (isNew && site.User != "")
+			if newCredential {
+				site.Password, site.PasswordEnv, site.PasswordCommand = "", passwordEnv, passwordCommand
+				site.ApiToken, site.ApiTokenEnv, site.ApiTokenCommand = "", apiTokenEnv, apiTokenCommand
+				if apiToken != "" {
+					if err := site.SetApiToken(apiToken); err != nil {
+						Fatal(err)
+					}
+				}
+			}
+			if err := site.ValidateLogin(); err != nil {
+				Fatal(err)
+			}
+			if newCredential && !site.UsesApiToken() && passwordEnv == "" && passwordCommand == "" {
+				if password == "" {
+					if password, err = readPassword("Password of " + site.User + ": "); err != nil {
+						Fatal(err)
+					}
+				}
+				if err := site.SetPassword(password); err != nil {
+					Fatal(err)
+				}
+			}
+
+			if isNew {
 				config.Sites = append(config.Sites, site)
 				if len(config.Sites) == 1 {
 					config.CurrentSite = site.Name
 				}
+			} else {
+				config.UpdateSite(site)
+				// the cached session may belong to the old endpoint or user
+				if err := forgetToken(site); err != nil {
+					Fatal(err)
+				}
+			}
+			// encrypt the base64 passwords of older versions as well
+			if _, err := config.migratePasswords(); err != nil {
+				Log(fmt.Sprintf("passwords are not migrated: %v", err))
 			}
 
-			saveConfig()
+			if err := saveConfig(); err != nil {
+				Fatal(err)
+			}
 		},
 	}
 	cmd.Flags().StringVarP(&endpoint, "endpoint", "e", "", "endpoint for the new site (https://{vcdmanager})")
 	cmd.Flags().StringVarP(&user, "user", "u", "", "user for the new site")
-	cmd.Flags().StringVarP(&password, "password", "p", "", "password for the new site user")
+	cmd.Flags().StringVarP(&password, "password", "p", "", "password for the new site user, stored encrypted (prompted when no password source is given)")
+	cmd.Flags().StringVar(&passwordEnv, "password-env", "", "environment variable to read the password from")
+	cmd.Flags().StringVar(&passwordCommand, "password-command", "", "command printing the password, e.g. 'pass show vcd/admin'")
+	cmd.Flags().StringVar(&apiToken, "api-token", "", "api token (refresh token) to log in with instead of the user and password, stored encrypted")
+	cmd.Flags().StringVar(&apiTokenEnv, "api-token-env", "", "environment variable to read the api token from")
+	cmd.Flags().StringVar(&apiTokenCommand, "api-token-command", "", "command printing the api token")
 	cmd.Flags().StringVarP(&orgname, "orgname", "o", "", "default org name")
-	cmd.MarkFlagRequired("endpoint")
-	cmd.MarkFlagRequired("user")
-	cmd.MarkFlagRequired("password")
-	cmd.MarkFlagRequired("orgname")
+	cmd.Flags().BoolVar(&insecure, "insecure", false, "skip verification of the server certificate")
+	cmd.Flags().StringVar(&caFile, "ca-file", "", "PEM file of CA certificates to trust for the site")
+	cmd.Flags().StringVar(&fingerprint, "fingerprint", "", "SHA-256 fingerprint of the server certificate to pin")
+	cmd.Flags().DurationVar(&timeout, "timeout", 0, "timeout of each request to the site (0 for the default 30s)")
+
+	return cmd
+}
+
+func NewCmdConfigUseSite() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "use-site ${SITE_NAME}",
+		Short: "switch the current site",
+		Args:  cobra.ExactArgs(1),
+		ValidArgsFunction: func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+			if len(args) != 0 {
+				return nil, cobra.ShellCompDirectiveNoFileComp
+			}
+			return completeSiteNames()
+		},
+		Run: func(cmd *cobra.Command, args []string) {
+			if _, err := config.GetSite(args[0]); err != nil {
+				Fatal(err)
+			}
+			config.CurrentSite = args[0]
+			if ctx, err := config.GetContext(config.CurrentContext); err == nil && ctx.Site != args[0] {
+				config.CurrentContext = ""
+			}
+			if err := saveConfig(); err != nil {
+				Fatal(err)
+			}
+		},
+	}
+	return cmd
+}
+
+func NewCmdConfigDeleteSite() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "delete-site ${SITE_NAME}",
+		Short: "delete vcd site configuration",
+		Args:  cobra.ExactArgs(1),
+		ValidArgsFunction: func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+			if len(args) != 0 {
+				return nil, cobra.ShellCompDirectiveNoFileComp
+			}
+			return completeSiteNames()
+		},
+		Run: func(cmd *cobra.Command, args []string) {
+			site, err := config.GetSite(args[0])
+			if err != nil {
+				Fatal(err)
+			}
+
+			sites := []Site{}
+			for _, s := range config.Sites {
+				if s.Name != site.Name {
+					sites = append(sites, s)
+				}
+			}
+			config.Sites = sites
+			if config.CurrentSite == site.Name {
+				config.CurrentSite = ""
+			}
+			contexts := []Context{}
+			for _, ctx := range config.Contexts {
+				if ctx.Site != site.Name {
+					contexts = append(contexts, ctx)
+				} else if ctx.Name == config.CurrentContext {
+					config.CurrentContext = ""
+				}
+			}
+			config.Contexts = contexts
+
+			if err := forgetToken(site); err != nil {
+				Fatal(err)
+			}
+			if err := saveConfig(); err != nil {
+				Fatal(err)
+			}
+		},
+	}
+	return cmd
+}
+
+func NewCmdConfigRenameSite() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "rename-site ${SITE_NAME} ${NEW_SITE_NAME}",
+		Short: "rename vcd site configuration",
+		Args:  cobra.ExactArgs(2),
+		ValidArgsFunction: func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+			if len(args) != 0 {
+				return nil, cobra.ShellCompDirectiveNoFileComp
+			}
+			return completeSiteNames()
+		},
+		Run: func(cmd *cobra.Command, args []string) {
+			oldName, newName := args[0], args[1]
+			if _, err := config.GetSite(newName); err == nil {
+				Fatal(fmt.Sprintf("site '%s' already exists", newName))
+			}
+			site, err := config.GetSite(oldName)
+			if err != nil {
+				Fatal(err)
+			}
+
+			// the cached session is keyed by the old name
+			if err := forgetToken(site); err != nil {
+				Fatal(err)
+			}
+			for i, s := range config.Sites {
+				if s.Name == oldName {
+					config.Sites[i].Name = newName
+				}
+			}
+			if config.CurrentSite == oldName {
+				config.CurrentSite = newName
+			}
+			for i, ctx := range config.Contexts {
+				if ctx.Site == oldName {
+					config.Contexts[i].Site = newName
+				}
+			}
+
+			if err := saveConfig(); err != nil {
+				Fatal(err)
+			}
+		},
+	}
+	return cmd
+}
+
+func NewCmdConfigTestSite() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "test-site [${SITE_NAME}]",
+		Short: "log in to the site and show the session",
+		Args:  cobra.MaximumNArgs(1),
+		ValidArgsFunction: func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+			if len(args) != 0 {
+				return nil, cobra.ShellCompDirectiveNoFileComp
+			}
+			return completeSiteNames()
+		},
+		Run: func(cmd *cobra.Command, args []string) {
+			site, err := config.GetCurrentSite()
+			if len(args) > 0 {
+				site, err = config.GetSite(args[0])
+			}
+			if err != nil {
+				Fatal(err)
+			}
+
+			// always a fresh login, to verify the credential
+			vcdClient, err := newVcdClient(site)
+			if err != nil {
+				Fatal(err)
+			}
+			if err := vcdClient.prepareApiVersion(); err != nil {
+				Fatal(err)
+			}
+			if err := vcdClient.Login(); err != nil {
+				Fatal(err)
+			}
+			session, err := getCurrentSession(vcdClient)
+			// not deferred: Fatal exits without running the deferred calls
+			vcdClient.Logout()
+			if err != nil {
+				Fatal(err)
+			}
 
+			fmt.Println("Site: " + site.Name)
+			fmt.Println("Endpoint: " + site.Endpoint)
+			fmt.Println("ApiVersion: " + vcdClient.site.ApiVersion)
+			fmt.Println("User: " + session.User.Name)
+			fmt.Println("Org: " + session.Org.Name)
+			fmt.Println("Roles: " + strings.Join(session.Roles, ", "))
+		},
+	}
 	return cmd
 }
 
+// CurrentSession is the user of a session and its roles.
+type CurrentSession struct {
+	User  ReferenceJson `json:"user"`
+	Org   ReferenceJson `json:"org"`
+	Roles []string      `json:"roles"`
+}
+
+func getCurrentSession(c *VcdClient) (CurrentSession, error) {
+	var session CurrentSession
+	res, err := c.Request("GET", "/cloudapi/1.0.0/sessions/current", nil, nil)
+	if err != nil {
+		return session, err
+	}
+	if err := json.Unmarshal(res.Body, &session); err != nil {
+		return session, responseError(res, err)
+	}
+	return session, nil
+}
+
 func NewCmdConfigGetSites() *cobra.Command {
 	cmd := &cobra.Command{
 		Use:   "get-sites",
@@ -85,13 +389,14 @@ func NewCmdConfigGetSites() *cobra.Command {
 				data = append(data, []string{current, s.Name, s.Endpoint, s.User, s.OrgName})
 			}
 
-			PrityPrint(header, data)
+			PrintResult(header, data, nil)
 		},
 	}
+	addOutputFlag(cmd)
 	return cmd
 }
 
-func initConfig() {
+func initConfig() error {
 	if configFilePath == "" {
 		configFilePath = defaultConfigFilePath()
 	}
@@ -100,27 +405,26 @@ func initConfig() {
 
 	if err := viper.ReadInConfig(); err != nil {
 		config = Config{}
-		return
+		return nil
 	}
 
-	if err := viper.Unmarshal(&config); err != nil {
-		log.Fatal(err)
-	}
+	return viper.Unmarshal(&config)
 }
 
-func saveConfig() {
+func saveConfig() error {
 	if configFilePath == "" {
 		configFilePath = defaultConfigFilePath()
 	}
 
 	file, err := json.MarshalIndent(config, "", "  ")
 	if err != nil {
-		log.Fatal(err)
+		return err
 	}
-	err = os.WriteFile(configFilePath, file, 0644)
-	if err != nil {
-		log.Fatal(err)
+	// the config holds credentials
+	if err := os.WriteFile(configFilePath, file, 0600); err != nil {
+		return err
 	}
+	return os.Chmod(configFilePath, 0600)
 }
 
 func defaultConfigFilePath() string {
@@ -132,26 +436,51 @@ func defaultConfigFilePath() string {
 }
 
 type Config struct {
-	CurrentSite string `json:"current-site" mapstructure:"current-site"`
-	Sites       []Site `json:"sites"`
+	CurrentSite    string    `json:"current-site" mapstructure:"current-site"`
+	Sites          []Site    `json:"sites"`
+	CurrentContext string    `json:"current-context,omitempty" mapstructure:"current-context"`
+	Contexts       []Context `json:"contexts,omitempty"`
 }
 
 type Site struct {
-	Name       string `json:"name"`
-	Endpoint   string `json:"endpoint"`
-	User       string `json:"user"`
-	Password   string `json:"password"`
-	OrgName    string `json:"orgname"`
-	ApiVersion string `json:"apiversion"`
+	Name     string `json:"name"`
+	Endpoint string `json:"endpoint"`
+	User     string `json:"user"`
+	Password string `json:"password"`
+	// PasswordEnv and PasswordCommand are used instead of Password when set.
+	PasswordEnv     string `json:"passwordEnv,omitempty"`
+	PasswordCommand string `json:"passwordCommand,omitempty"`
+	// ApiToken is a refresh token exchanged for an access token at login,
+	// used instead of the user and password. The sources are as for passwords.
+	ApiToken        string `json:"apiToken,omitempty"`
+	ApiTokenEnv     string `json:"apiTokenEnv,omitempty"`
+	ApiTokenCommand string `json:"apiTokenCommand,omitempty"`
+	OrgName         string `json:"orgname"`
+	ApiVersion      string `json:"apiversion"`
+	// Insecure skips the verification of the server certificate.
+	Insecure bool `json:"insecure,omitempty"`
+	// CaFile is a PEM bundle trusted in addition to the system roots.
+	CaFile string `json:"caFile,omitempty"`
+	// Fingerprint pins the SHA-256 fingerprint of the server certificate.
+	Fingerprint string `json:"fingerprint,omitempty"`
+	// Timeout of each request, e.g. "2m".
+	Timeout string `json:"timeout,omitempty"`
 }
 
+// GetCurrentSite returns the site selected by --site, VCDCTL_SITE or
+// current-site, in this order.
 func (c *Config) GetCurrentSite() (Site, error) {
-	for _, s := range c.Sites {
-		if s.Name == c.CurrentSite {
-			return s, nil
-		}
+	name := siteOverride
+	if name == "" {
+		name = os.Getenv("VCDCTL_SITE")
+	}
+	if name == "" {
+		name = c.CurrentSite
 	}
-	return Site{}, fmt.Errorf("site '%s' not found", c.CurrentSite)
+	if name == "" {
+		return Site{}, fmt.Errorf("no current site, select one with 'vcdctl config use-site'")
+	}
+	return c.GetSite(name)
 }
 
 func (c *Config) GetSite(name string) (Site, error) {
@@ -163,14 +492,114 @@ func (c *Config) GetSite(name string) (Site, error) {
 	return Site{}, fmt.Errorf("site '%s' not found", name)
 }
 
-func (s *Site) GetCredential() string {
-	passwordText, err := base64.StdEncoding.DecodeString(s.Password)
+func (c *Config) GetSiteNames() []string {
+	names := []string{}
+	for _, s := range c.Sites {
+		names = append(names, s.Name)
+	}
+	return names
+}
+
+func completeSiteNames() ([]string, cobra.ShellCompDirective) {
+	if err := initConfig(); err != nil {
+		return nil, cobra.ShellCompDirectiveError
+	}
+	return config.GetSiteNames(), cobra.ShellCompDirectiveNoFileComp
+}
+
+// UpdateSite replaces the site of the same name.
+func (c *Config) UpdateSite(site Site) {
+	for i, s := range c.Sites {
+		if s.Name == site.Name {
+			c.Sites[i] = site
+		}
+	}
+}
+
+func (s *Site) GetCredential() (string, error) {
+	user, org, err := s.LoginUser()
+	if err != nil {
+		return "", err
+	}
+	password, err := s.GetPassword()
+	if err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString([]byte(user + "@" + org + ":" + password)), nil
+}
+
+// LoginUser splits "user@org". When the user has no org, the org of the
+// site is used. Provider users log in to the "system" org.
+func (s *Site) LoginUser() (string, string, error) {
+	user, org := s.User, s.OrgName
+	if i := strings.LastIndex(s.User, "@"); i >= 0 {
+		user, org = s.User[:i], s.User[i+1:]
+	}
+	if user == "" {
+		return "", "", fmt.Errorf("user of site '%s' is empty", s.Name)
+	}
+	if org == "" {
+		return "", "", fmt.Errorf("org of user '%s' of site '%s' is unknown: use %s@{org} or set --orgname", s.User, s.Name, s.User)
+	}
+	return user, org, nil
+}
+
+// ValidateLogin checks the user and org combination before it is saved.
+func (s *Site) ValidateLogin() error {
+	if s.UsesApiToken() {
+		if s.OrgName == "" {
+			return fmt.Errorf("--orgname is required to log in with an api token (system for provider)")
+		}
+		return nil
+	}
+	_, org, err := s.LoginUser()
+	if err != nil {
+		return err
+	}
+	// a tenant user can only work in its own org, a provider user in any
+	if !isSystemOrg(org) && s.OrgName != "" && !strings.EqualFold(org, s.OrgName) {
+		return fmt.Errorf("user '%s' belongs to org '%s' and can not use org '%s': set --orgname %s, or log in as a provider user (user@system)", s.User, org, s.OrgName, org)
+	}
+	return nil
+}
+
+func isSystemOrg(org string) bool {
+	return strings.EqualFold(org, "system")
+}
+
+// GetPassword returns the password from the configured source: the
+// environment variable, the command, or the encrypted store.
+func (s *Site) GetPassword() (string, error) {
+	return s.getSecret("password", s.Password, s.PasswordEnv, s.PasswordCommand)
+}
+
+// SetPassword stores the password encrypted with the passphrase.
+func (s *Site) SetPassword(password string) error {
+	encrypted, err := encryptSiteSecret(password)
 	if err != nil {
-		log.Fatal(err)
+		return err
 	}
-	return base64.StdEncoding.EncodeToString([]byte(s.User + ":" + string(passwordText)))
+	s.Password = encrypted
+	return nil
+}
+
+// UsesApiToken reports whether the site logs in with an api token instead
+// of the user and password.
+func (s *Site) UsesApiToken() bool {
+	return s.ApiToken != "" || s.ApiTokenEnv != "" || s.ApiTokenCommand != ""
+}
+
+// GetApiToken returns the api token from the configured source, like GetPassword.
+func (s *Site) GetApiToken() (string, error) {
+	return s.getSecret("api token", s.ApiToken, s.ApiTokenEnv, s.ApiTokenCommand)
 }
 
-func (s *Site) SetPassword(password string) {
-	s.Password = base64.StdEncoding.EncodeToString([]byte(password))
+// SetApiToken stores the api token encrypted with the passphrase.
+func (s *Site) SetApiToken(token string) error {
+	encrypted, err := encryptSiteSecret(token)
+	if err != nil {
+		return err
+	}
+	s.ApiToken = encrypted
+	return nil
 }
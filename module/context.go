@@ -0,0 +1,229 @@
+package module
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+)
+
+// Context is a named set of defaults on a site, used by the commands when
+// --org, --orgvdc or --edge is not given.
+type Context struct {
+	Name   string `json:"name"`
+	Site   string `json:"site"`
+	Org    string `json:"org,omitempty"`
+	OrgVdc string `json:"orgvdc,omitempty"`
+	Edge   string `json:"edge,omitempty"`
+}
+
+func (c *Config) GetContext(name string) (Context, error) {
+	for _, ctx := range c.Contexts {
+		if ctx.Name == name {
+			return ctx, nil
+		}
+	}
+	return Context{}, fmt.Errorf("context '%s' not found", name)
+}
+
+func (c *Config) GetContextNames() []string {
+	names := []string{}
+	for _, ctx := range c.Contexts {
+		names = append(names, ctx.Name)
+	}
+	return names
+}
+
+// activeContext returns the current context when it belongs to the site in
+// use, e.g. not when another site is selected with --site.
+func activeContext() Context {
+	ctx, err := config.GetContext(config.CurrentContext)
+	if err != nil || ctx.Site != client.site.Name {
+		return Context{}
+	}
+	return ctx
+}
+
+// orgOrContext returns the name, or the org of the context, or the org of the site.
+func orgOrContext(name string) string {
+	if name != "" {
+		return name
+	}
+	if org := activeContext().Org; org != "" {
+		return org
+	}
+	return client.site.OrgName
+}
+
+// orgVdcOrContext returns the name, or the org vdc of the context.
+func orgVdcOrContext(name string) string {
+	if name != "" {
+		return name
+	}
+	return activeContext().OrgVdc
+}
+
+// edgeOrContext returns the name, or the edge of the context.
+func edgeOrContext(name string) string {
+	if name != "" {
+		return name
+	}
+	return activeContext().Edge
+}
+
+func completeContextNames() ([]string, cobra.ShellCompDirective) {
+	if err := initConfig(); err != nil {
+		return nil, cobra.ShellCompDirectiveError
+	}
+	return config.GetContextNames(), cobra.ShellCompDirectiveNoFileComp
+}
+
+func NewCmdConfigSetContext() *cobra.Command {
+	var site string
+	var org string
+	var orgvdc string
+	var edge string
+
+	cmd := &cobra.Command{
+		Use:   "set-context ${CONTEXT_NAME}",
+		Short: "add context, or update the given fields of the existing context",
+		Args:  cobra.ExactArgs(1),
+		ValidArgsFunction: func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+			if len(args) != 0 {
+				return nil, cobra.ShellCompDirectiveNoFileComp
+			}
+			return completeContextNames()
+		},
+		Run: func(cmd *cobra.Command, args []string) {
+			flags := cmd.Flags()
+			ctx, err := config.GetContext(args[0])
+			isNew := err != nil
+			if isNew {
+				if site == "" {
+					Fatal("--site is required for a new context")
+				}
+				ctx = Context{Name: args[0]}
+			}
+
+			if flags.Changed("site") {
+				if _, err := config.GetSite(site); err != nil {
+					Fatal(err)
+				}
+				ctx.Site = site
+			}
+			if flags.Changed("org") {
+				ctx.Org = org
+			}
+			if flags.Changed("orgvdc") {
+				ctx.OrgVdc = orgvdc
+			}
+			if flags.Changed("edge") {
+				ctx.Edge = edge
+			}
+
+			if isNew {
+				config.Contexts = append(config.Contexts, ctx)
+			} else {
+				for i, c := range config.Contexts {
+					if c.Name == ctx.Name {
+						config.Contexts[i] = ctx
+					}
+				}
+			}
+			if err := saveConfig(); err != nil {
+				Fatal(err)
+			}
+		},
+	}
+	// not -s/-o/-v/-e: --site is a global flag, and set-site uses -o
+	cmd.Flags().StringVar(&site, "site", "", "site of the context")
+	cmd.Flags().StringVar(&org, "org", "", "default org name (the org of the site when empty)")
+	cmd.Flags().StringVar(&orgvdc, "orgvdc", "", "default org vdc name")
+	cmd.Flags().StringVar(&edge, "edge", "", "default edge name")
+
+	cmd.RegisterFlagCompletionFunc("site", func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		return completeSiteNames()
+	})
+	return cmd
+}
+
+func NewCmdConfigUseContext() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "use-context ${CONTEXT_NAME}",
+		Short: "switch the current context (and so the current site)",
+		Args:  cobra.ExactArgs(1),
+		ValidArgsFunction: func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+			if len(args) != 0 {
+				return nil, cobra.ShellCompDirectiveNoFileComp
+			}
+			return completeContextNames()
+		},
+		Run: func(cmd *cobra.Command, args []string) {
+			ctx, err := config.GetContext(args[0])
+			if err != nil {
+				Fatal(err)
+			}
+			config.CurrentContext = ctx.Name
+			config.CurrentSite = ctx.Site
+			if err := saveConfig(); err != nil {
+				Fatal(err)
+			}
+		},
+	}
+	return cmd
+}
+
+func NewCmdConfigGetContexts() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "get-contexts",
+		Short: "show contexts",
+		Run: func(cmd *cobra.Command, args []string) {
+			header := []string{"Current", "Name", "Site", "Org", "OrgVdc", "Edge"}
+
+			var data [][]string
+			for _, ctx := range config.Contexts {
+				current := ""
+				if ctx.Name == config.CurrentContext {
+					current = "*"
+				}
+				data = append(data, []string{current, ctx.Name, ctx.Site, ctx.Org, ctx.OrgVdc, ctx.Edge})
+			}
+
+			PrintResult(header, data, config.Contexts)
+		},
+	}
+	addOutputFlag(cmd)
+	return cmd
+}
+
+func NewCmdConfigDeleteContext() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "delete-context ${CONTEXT_NAME}",
+		Short: "delete context",
+		Args:  cobra.ExactArgs(1),
+		ValidArgsFunction: func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+			if len(args) != 0 {
+				return nil, cobra.ShellCompDirectiveNoFileComp
+			}
+			return completeContextNames()
+		},
+		Run: func(cmd *cobra.Command, args []string) {
+			if _, err := config.GetContext(args[0]); err != nil {
+				Fatal(err)
+			}
+			contexts := []Context{}
+			for _, ctx := range config.Contexts {
+				if ctx.Name != args[0] {
+					contexts = append(contexts, ctx)
+				}
+			}
+			config.Contexts = contexts
+			if config.CurrentContext == args[0] {
+				config.CurrentContext = ""
+			}
+			if err := saveConfig(); err != nil {
+				Fatal(err)
+			}
+		},
+	}
+	return cmd
+}
@@ -3,6 +3,7 @@ package module
 import (
 	"encoding/json"
 	"encoding/xml"
+	"errors"
 	"fmt"
 	"strconv"
 	"strings"
@@ -27,6 +28,7 @@ func NewCmdCreate() *cobra.Command {
 		NewCmdCreateVAppNetwork(),
 		NewCmdCreateEdge(),
 	)
+	addWaitFlags(cmd)
 	return cmd
 }
 
@@ -48,11 +50,13 @@ func NewCmdCreateOrg() *cobra.Command {
 
 			data, err := json.Marshal(org)
 			if err != nil {
-				Fatal(nil)
+				Fatal(err)
 			}
 
 			header := map[string]string{"Content-Type": "application/json"}
-			client.Request("POST", "/cloudapi/1.0.0/orgs", header, data)
+			if _, err := client.Request("POST", "/cloudapi/1.0.0/orgs", header, data); err != nil {
+				Fatal(err)
+			}
 		},
 	}
 	return cmd
@@ -75,13 +79,14 @@ func NewCmdCreateOrgVdc() *cobra.Command {
 			}
 			vdcName := args[0]
 
-			if orgName == "" {
-				orgName = client.site.OrgName
-			}
+			orgName = orgOrContext(orgName)
 			if orgName == "" {
 				Fatal("org name not specified")
 			}
-			org := GetOrg(orgName)
+			org, err := GetOrg(orgName)
+			if err != nil {
+				Fatal(err)
+			}
 
 			storageProfile, err := GetStorageProfile(storagePolicyName, providerVdcName)
 			if err != nil {
@@ -152,8 +157,14 @@ func NewCmdCreateOrgVdc() *cobra.Command {
 			}
 
 			header := map[string]string{"Content-Type": "application/vnd.vmware.admin.createVdcParams+xml"}
-			res := client.Request("POST", fmt.Sprintf("/api/admin/org/%s/vdcsparams", org.Id), header, data)
+			res, err := client.Request("POST", fmt.Sprintf("/api/admin/org/%s/vdcsparams", org.Id), header, data)
+			if err != nil {
+				Fatal(err)
+			}
 			fmt.Println(string(res.Body))
+			if err := waitResponseTask(res); err != nil {
+				Fatal(err)
+			}
 		},
 	}
 	cmd.PersistentFlags().StringVarP(&orgName, "org", "", "", "org name")
@@ -185,6 +196,10 @@ func NewCmdCreateOrgVdcNetwork() *cobra.Command {
 				return
 			}
 			networkName := args[0]
+			orgvdcName = orgVdcOrContext(orgvdcName)
+			if orgvdcName == "" {
+				Fatal("org vdc name not specified")
+			}
 
 			vdc, err := GetVdc(orgvdcName)
 			if err != nil {
@@ -229,7 +244,7 @@ func NewCmdCreateOrgVdcNetwork() *cobra.Command {
 			}
 
 			if networkType == "NAT_ROUTED" {
-				edge, err := GetEdge(gatewayName, orgvdcName)
+				edge, err := GetEdge(edgeOrContext(gatewayName), orgvdcName)
 				if err != nil {
 					Fatal(err)
 				}
@@ -250,34 +265,43 @@ func NewCmdCreateOrgVdcNetwork() *cobra.Command {
 			}
 
 			header := map[string]string{"Content-Type": "application/json"}
-			res := client.Request("POST", "/cloudapi/1.0.0/orgVdcNetworks", header, data)
+			res, err := client.Request("POST", "/cloudapi/1.0.0/orgVdcNetworks", header, data)
+			if err != nil {
+				Fatal(err)
+			}
 			fmt.Println(string(res.Body))
+			if err := waitResponseTask(res); err != nil {
+				Fatal(err)
+			}
 		},
 	}
-	cmd.PersistentFlags().StringVarP(&orgvdcName, "orgvdc", "", "", "org vdc name (required)")
+	cmd.PersistentFlags().StringVarP(&orgvdcName, "orgvdc", "", "", "org vdc name (default: the org vdc of the context)")
 	cmd.PersistentFlags().StringVarP(&networkType, "type", "", "", "network type (NAT_ROUTED | ISOLATED | DIRECT) (required)")
 	cmd.PersistentFlags().StringVarP(&gatewayCidr, "cidr", "", "", "gateway cidr")
-	cmd.PersistentFlags().StringVarP(&gatewayName, "gateway", "", "", "gateway name (NAT_ROUTED only)")
+	cmd.PersistentFlags().StringVarP(&gatewayName, "gateway", "", "", "gateway name (NAT_ROUTED only, default: the edge of the context)")
 	cmd.PersistentFlags().BoolVarP(&distributed, "distributed", "", false, "enable distributed connection (NAT_ROUTED only, default false)")
 	cmd.PersistentFlags().StringVarP(&externalNetworkName, "external-network", "", "", "external network name (DIRECT only)")
-	cmd.MarkFlagRequired("orgvdc")
 	cmd.MarkFlagRequired("type")
 
 	cmd.RegisterFlagCompletionFunc("orgvdc", func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
 		initClient()
-		return GetOvdcNames(), cobra.ShellCompDirectiveNoFileComp
+		return completeNames(GetOvdcNames())
 	})
 	cmd.RegisterFlagCompletionFunc("type", func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
 		return []string{"NAT_ROUTED", "ISOLATED", "DIRECT"}, cobra.ShellCompDirectiveNoFileComp
 	})
 	cmd.RegisterFlagCompletionFunc("gateway", func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
 		initClient()
-		return GetEdgeNames(orgvdcName), cobra.ShellCompDirectiveNoFileComp
+		return completeNames(GetEdgeNames(orgVdcOrContext(orgvdcName)))
 	})
 	cmd.RegisterFlagCompletionFunc("external-network", func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
 		initClient()
+		externalNetworks, err := GetExternalNetworks()
+		if err != nil {
+			return nil, cobra.ShellCompDirectiveError
+		}
 		externalNetworkNames := []string{}
-		for _, nw := range GetExternalNetworks() {
+		for _, nw := range externalNetworks {
 			externalNetworkNames = append(externalNetworkNames, nw.Name)
 		}
 		return externalNetworkNames, cobra.ShellCompDirectiveNoFileComp
@@ -288,6 +312,7 @@ func NewCmdCreateOrgVdcNetwork() *cobra.Command {
 func NewCmdCreateEdge() *cobra.Command {
 	var orgvdcName string
 	var providerGatewayName string
+	var usingIpSpace bool
 	//var primaryIp string
 	//var ipRange string
 	cmd := &cobra.Command{
@@ -300,8 +325,16 @@ func NewCmdCreateEdge() *cobra.Command {
 				return
 			}
 			edgeName := args[0]
+			orgvdcName = orgVdcOrContext(orgvdcName)
+			if orgvdcName == "" {
+				Fatal("org vdc name not specified")
+			}
+			// only a not found edge is absent, other errors must not create it
+			var notFoundError *NotFoundError
 			if _, err := GetEdge(edgeName, orgvdcName); err == nil {
 				Fatal(fmt.Sprintf("%s is already exist", edgeName))
+			} else if !errors.As(err, &notFoundError) {
+				Fatal(err)
 			}
 
 			vdc, err := GetVdc(orgvdcName)
@@ -313,6 +346,21 @@ func NewCmdCreateEdge() *cobra.Command {
 			if err != nil {
 				Fatal(err)
 			}
+
+			// VRF-lite backed provider gateways are connected as VRF-lite uplinks
+			backingType := providerGateway.BackingType()
+			vrfLiteBacked := backingType == "NSXT_VRF_TIER0"
+			if vrfLiteBacked {
+				if err := RequireApiVersion(apiVersionVrfLiteUplink, "VRF-lite backed uplink"); err != nil {
+					Fatal(err)
+				}
+			}
+			if usingIpSpace {
+				if err := RequireApiVersion(apiVersionIpSpace, "IP space uplink"); err != nil {
+					Fatal(err)
+				}
+			}
+
 			providerGatewaySubnet := providerGateway.Subnets.Values[0]
 			routerLink := EdgeGatewayUplink{
 				UplinkId:   providerGateway.Urn,
@@ -331,9 +379,9 @@ func NewCmdCreateEdge() *cobra.Command {
 				},
 				Dedicated:     false,
 				Connected:     false,
-				UsingIpSpace:  false,
-				VrfLiteBacked: false,
-				BackingType:   "NSXT_TIER0",
+				UsingIpSpace:  usingIpSpace,
+				VrfLiteBacked: vrfLiteBacked,
+				BackingType:   backingType,
 			}
 
 			newEdge := struct {
@@ -360,25 +408,35 @@ func NewCmdCreateEdge() *cobra.Command {
 			}
 
 			header := map[string]string{"Content-Type": "application/json"}
-			res := client.Request("POST", "/cloudapi/1.0.0/edgeGateways", header, data)
+			res, err := client.Request("POST", "/cloudapi/1.0.0/edgeGateways", header, data)
+			if err != nil {
+				Fatal(err)
+			}
 			fmt.Println(string(res.Body))
+			if err := waitResponseTask(res); err != nil {
+				Fatal(err)
+			}
 		},
 	}
-	cmd.PersistentFlags().StringVarP(&orgvdcName, "orgvdc", "", "", "org vdc name (required)")
+	cmd.PersistentFlags().StringVarP(&orgvdcName, "orgvdc", "", "", "org vdc name (default: the org vdc of the context)")
 	cmd.PersistentFlags().StringVarP(&providerGatewayName, "provider-gateway", "", "", "provider gateway name (required)")
+	cmd.PersistentFlags().BoolVarP(&usingIpSpace, "ip-space", "", false, "allocate the uplink ips from IP spaces (API 37.1 or later)")
 	//cmd.PersistentFlags().StringVarP(&primaryIp, "primary-ip", "", "", "primary ip address")
 	//cmd.PersistentFlags().StringVarP(&ipRange, "ip-range", "", "", "ip range")
-	cmd.MarkFlagRequired("orgvdc")
 	cmd.MarkFlagRequired("provider-gateway")
 
 	cmd.RegisterFlagCompletionFunc("orgvdc", func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
 		initClient()
-		return GetOvdcNames(), cobra.ShellCompDirectiveNoFileComp
+		return completeNames(GetOvdcNames())
 	})
 	cmd.RegisterFlagCompletionFunc("provider-gateway", func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
 		initClient()
+		providerGateways, err := GetProviderGateways()
+		if err != nil {
+			return nil, cobra.ShellCompDirectiveError
+		}
 		providerGatewayNames := []string{}
-		for _, gw := range GetProviderGateways() {
+		for _, gw := range providerGateways {
 			providerGatewayNames = append(providerGatewayNames, gw.Name)
 		}
 		return providerGatewayNames, cobra.ShellCompDirectiveNoFileComp
@@ -409,13 +467,7 @@ func NewCmdCreateVAppNetwork() *cobra.Command {
 				return nil, cobra.ShellCompDirectiveNoFileComp
 			}
 			initClient()
-			vapps := GetVApps()
-			vappNames := []string{}
-			for _, vapp := range vapps {
-				vappNames = append(vappNames, vapp.Name)
-			}
-
-			return vappNames, cobra.ShellCompDirectiveNoFileComp
+			return completeNames(GetVAppNames())
 		},
 		Run: func(cmd *cobra.Command, args []string) {
 			//
@@ -7,23 +7,26 @@ import (
 )
 
 func NewCmdDelete() *cobra.Command {
+	r := &RawRequest{Method: "DELETE"}
 	cmd := &cobra.Command{
 		Use:   "delete",
 		Short: "exec delete api",
+		Long:  "exec delete api\n\n" + rawApiExample,
 		Args:  cobra.ExactArgs(1),
 		PersistentPreRun: func(cmd *cobra.Command, args []string) {
 			initClient()
 		},
 		Run: func(cmd *cobra.Command, args []string) {
-			api := args[0]
-			res := client.Request("DELETE", api, nil, nil)
-			fmt.Println(string(res.Body))
+			r.Path = args[0]
+			r.Do()
 		},
 	}
 	cmd.AddCommand(
 		NewCmdDeleteOrg(),
 		NewCmdDeleteOrgVdcNetwork(),
 	)
+	addWaitFlags(cmd)
+	addRawRequestFlags(cmd, r, true)
 	return cmd
 }
 
@@ -36,8 +39,17 @@ func NewCmdDeleteOrg() *cobra.Command {
 				cmd.Help()
 				return
 			}
-			org := GetOrg(args[0])
-			client.Request("DELETE", fmt.Sprintf("/cloudapi/1.0.0/orgs/urn:vcloud:org:%s", org.Id), nil, nil)
+			org, err := GetOrg(args[0])
+			if err != nil {
+				Fatal(err)
+			}
+			res, err := client.Request("DELETE", fmt.Sprintf("/cloudapi/1.0.0/orgs/urn:vcloud:org:%s", org.Id), nil, nil)
+			if err != nil {
+				Fatal(err)
+			}
+			if err := waitResponseTask(res); err != nil {
+				Fatal(err)
+			}
 		},
 	}
 	return cmd
@@ -57,11 +69,15 @@ func NewCmdDeleteOrgVdcNetwork() *cobra.Command {
 			}
 			initClient()
 			networkNames := []string{}
-			vdc, err := GetVdc(orgvdcName)
+			vdc, err := GetVdc(orgVdcOrContext(orgvdcName))
 			if err != nil {
-				Fatal(err)
+				return nil, cobra.ShellCompDirectiveError
+			}
+			networks, err := GetOrgVdcNetworks(vdc.Id)
+			if err != nil {
+				return nil, cobra.ShellCompDirectiveError
 			}
-			for _, nw := range GetOrgVdcNetworks(vdc.Id) {
+			for _, nw := range networks {
 				networkNames = append(networkNames, nw.Name)
 			}
 			return networkNames, cobra.ShellCompDirectiveNoFileComp
@@ -72,6 +88,10 @@ func NewCmdDeleteOrgVdcNetwork() *cobra.Command {
 				return
 			}
 			networkName := args[0]
+			orgvdcName = orgVdcOrContext(orgvdcName)
+			if orgvdcName == "" {
+				Fatal("org vdc name not specified")
+			}
 
 			vdc, err := GetVdc(orgvdcName)
 			if err != nil {
@@ -82,15 +102,20 @@ func NewCmdDeleteOrgVdcNetwork() *cobra.Command {
 			if err != nil {
 				Fatal(err)
 			}
-			client.Request("DELETE", fmt.Sprintf("/cloudapi/1.0.0/orgVdcNetworks/%s", network.Urn), nil, nil)
+			res, err := client.Request("DELETE", fmt.Sprintf("/cloudapi/1.0.0/orgVdcNetworks/%s", network.Urn), nil, nil)
+			if err != nil {
+				Fatal(err)
+			}
+			if err := waitResponseTask(res); err != nil {
+				Fatal(err)
+			}
 		},
 	}
-	cmd.PersistentFlags().StringVarP(&orgvdcName, "orgvdc", "", "", "org vdc name (required)")
-	cmd.MarkFlagRequired("orgvdc")
+	cmd.PersistentFlags().StringVarP(&orgvdcName, "orgvdc", "", "", "org vdc name (default: the org vdc of the context)")
 
 	cmd.RegisterFlagCompletionFunc("orgvdc", func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
 		initClient()
-		return GetOvdcNames(), cobra.ShellCompDirectiveNoFileComp
+		return completeNames(GetOvdcNames())
 	})
 	return cmd
 }
\ No newline at end of file
@@ -0,0 +1,127 @@
+package module
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// Exit codes of vcdctl, so that scripts can branch on the kind of failure.
+const (
+	ExitError      = 1
+	ExitAuth       = 2
+	ExitNotFound   = 3
+	ExitConflict   = 4
+	ExitValidation = 5
+	ExitServer     = 6
+)
+
+// ApiError is returned when vCD answers a request with a non-2xx status.
+type ApiError struct {
+	StatusCode     int
+	Method         string
+	Path           string
+	MajorErrorCode string
+	MinorErrorCode string
+	Message        string
+	StackTrace     string
+}
+
+func (e *ApiError) Error() string {
+	msg := strings.Join(strings.Fields(e.Message), " ")
+	if msg == "" {
+		msg = http.StatusText(e.StatusCode)
+	}
+	if e.MinorErrorCode != "" {
+		msg = fmt.Sprintf("[%s] %s", e.MinorErrorCode, msg)
+	}
+	return fmt.Sprintf("%s %s: %d %s", e.Method, e.Path, e.StatusCode, msg)
+}
+
+// ExitCode returns the exit code for the http status of the error.
+func (e *ApiError) ExitCode() int {
+	switch {
+	case e.StatusCode == http.StatusUnauthorized || e.StatusCode == http.StatusForbidden:
+		return ExitAuth
+	case e.StatusCode == http.StatusNotFound:
+		return ExitNotFound
+	case e.StatusCode == http.StatusConflict:
+		return ExitConflict
+	case e.StatusCode >= 500:
+		return ExitServer
+	case e.StatusCode >= 400:
+		return ExitValidation
+	}
+	return ExitError
+}
+
+// vcdErrorBody covers both the legacy api <Error> element and the cloudapi
+// json error.
+type vcdErrorBody struct {
+	MajorErrorCode string `xml:"majorErrorCode,attr" json:"majorErrorCode"`
+	MinorErrorCode string `xml:"minorErrorCode,attr" json:"minorErrorCode"`
+	Message        string `xml:"message,attr" json:"message"`
+	StackTrace     string `xml:"stackTrace,attr" json:"stackTrace"`
+}
+
+func newApiError(res *Response) *ApiError {
+	apiError := &ApiError{StatusCode: res.StatusCode}
+	if res.Request != nil {
+		apiError.Method = res.Request.Method
+		apiError.Path = res.Request.URL.Path
+	}
+
+	var body vcdErrorBody
+	trimmed := strings.TrimSpace(string(res.Body))
+	var err error
+	if strings.HasPrefix(trimmed, "{") {
+		err = json.Unmarshal(res.Body, &body)
+	} else if strings.HasPrefix(trimmed, "<") {
+		err = xml.Unmarshal(res.Body, &body)
+	}
+	if err != nil || body.Message == "" {
+		apiError.Message = trimmed
+		return apiError
+	}
+
+	apiError.MajorErrorCode = body.MajorErrorCode
+	apiError.MinorErrorCode = body.MinorErrorCode
+	apiError.Message = body.Message
+	apiError.StackTrace = body.StackTrace
+	return apiError
+}
+
+// responseError is used when the body of a successful response can not be
+// decoded.
+func responseError(res *Response, err error) error {
+	return fmt.Errorf("failed to parse response of %s: %w", res.Request.URL.Path, err)
+}
+
+// NotFoundError is returned by the getters when no object matches.
+type NotFoundError struct {
+	Kind   string
+	Name   string
+	Parent string
+}
+
+func (e *NotFoundError) Error() string {
+	if e.Parent != "" {
+		return fmt.Sprintf("%s \"%s\" not found at %s", e.Kind, e.Name, e.Parent)
+	}
+	return fmt.Sprintf("%s \"%s\" not found", e.Kind, e.Name)
+}
+
+func exitCode(err error) int {
+	var apiError *ApiError
+	if errors.As(err, &apiError) {
+		return apiError.ExitCode()
+	}
+	var notFoundError *NotFoundError
+	if errors.As(err, &notFoundError) {
+		return ExitNotFound
+	}
+	return ExitError
+}
@@ -1,7 +1,6 @@
 package module
 
 import (
-	"encoding/json"
 	"encoding/xml"
 	"fmt"
 	"sort"
@@ -13,9 +12,11 @@ import (
 )
 
 func NewCmdGet() *cobra.Command {
+	r := &RawRequest{Method: "GET"}
 	cmd := &cobra.Command{
 		Use:   "get",
 		Short: "get resources or exec get api",
+		Long:  "get resources or exec get api\n\n" + rawApiExample,
 		Args:  cobra.MaximumNArgs(1),
 		PersistentPreRun: func(cmd *cobra.Command, args []string) {
 			initClient()
@@ -25,12 +26,11 @@ func NewCmdGet() *cobra.Command {
 				cmd.Help()
 				return
 			}
-			api := args[0]
-			if validateApi(api) {
-				res := client.Request("GET", api, nil, nil)
-				fmt.Println(string(res.Body))
+			r.Path = args[0]
+			if validateApi(r.Path) {
+				r.Do()
 			} else {
-				Fatal("\"" + api + "\" is not a valid command or api")
+				Fatal("\"" + r.Path + "\" is not a valid command or api")
 			}
 		},
 	}
@@ -47,6 +47,8 @@ func NewCmdGet() *cobra.Command {
 		NewCmdGetVAppVmNetwork(),
 		NewCmdGetTask(),
 	)
+	addOutputFlag(cmd)
+	addRawRequestFlags(cmd, r, false)
 	return cmd
 }
 
@@ -57,12 +59,17 @@ func NewCmdGetOrg() *cobra.Command {
 		Run: func(cmd *cobra.Command, args []string) {
 			header := []string{"Name", "Id", "href"}
 			var data [][]string
-			for _, org := range GetOrgs() {
+			orgs, err := QueryOrgs(listQuery())
+			if err != nil {
+				Fatal(err)
+			}
+			for _, org := range orgs {
 				data = append(data, []string{org.Name, org.Id, org.Href})
 			}
-			PrityPrint(header, data)
+			PrintResult(header, data, orgs)
 		},
 	}
+	addListFlags(cmd)
 	return cmd
 }
 
@@ -74,7 +81,11 @@ func NewCmdGetOrgVdc() *cobra.Command {
 		Run: func(cmd *cobra.Command, args []string) {
 			header := []string{"Name", "Id", "IsEnabled", "Org", "ProviderVdc", "Vc", "NetworkType", "VApps", "VMs", "VAppTemplates"}
 			var data [][]string
-			for _, vdc := range GetOrgVdcs() {
+			vdcs, err := QueryOrgVdcs(listQuery())
+			if err != nil {
+				Fatal(err)
+			}
+			for _, vdc := range vdcs {
 				data = append(data, []string{
 					vdc.Name,
 					vdc.Id,
@@ -87,33 +98,45 @@ func NewCmdGetOrgVdc() *cobra.Command {
 					strconv.Itoa(vdc.NumberOfVMs),
 					strconv.Itoa(vdc.NumberOfVAppTemplates)})
 			}
-			PrityPrint(header, data)
+			PrintResult(header, data, vdcs)
 		},
 	}
+	addListFlags(cmd)
 	return cmd
 }
 
 func NewCmdGetOrgVdcNetwork() *cobra.Command {
 	cmd := &cobra.Command{
-		Use:     "vdc-network ${VDC_NAME}",
+		Use:     "vdc-network [${VDC_NAME}]",
 		Short:   "Get VdcNetwork [vn]",
 		Aliases: []string{"vn"},
-		Args:    cobra.ExactArgs(1),
+		Args:    cobra.MaximumNArgs(1),
 		ValidArgsFunction: func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
 			if len(args) != 0 {
 				return nil, cobra.ShellCompDirectiveNoFileComp
 			}
 			initClient()
-			return GetOvdcNames(), cobra.ShellCompDirectiveNoFileComp
+			return completeNames(GetOvdcNames())
 		},
 		Run: func(cmd *cobra.Command, args []string) {
-			vdcName := args[0]
+			vdcName := ""
+			if len(args) > 0 {
+				vdcName = args[0]
+			}
+			vdcName = orgVdcOrContext(vdcName)
+			if vdcName == "" {
+				Fatal("org vdc name not specified")
+			}
 			orgVdc, err := GetVdc(vdcName)
 			if err != nil {
 				Fatal(err)
 			}
 			var data [][]string
-			for _, nw := range GetOrgVdcNetworks(orgVdc.Id) {
+			networks, err := QueryOrgVdcNetworks(orgVdc.Href, listQuery())
+			if err != nil {
+				Fatal(err)
+			}
+			for _, nw := range networks {
 				ipScope := nw.Configuration.IpScopes.IpScope[0]
 				data = append(data, []string{
 					nw.Name,
@@ -128,9 +151,10 @@ func NewCmdGetOrgVdcNetwork() *cobra.Command {
 					nw.IsShared,
 					ipScope.IsInherited})
 			}
-			PrityPrint([]string{"Name", "Id", "Org", "Vdc", "DefaultGateway", "Dns1", "Dns2", "DnsSuffix", "FenceMode", "IsShared", "IsIpScopeInherited"}, data)
+			PrintResult([]string{"Name", "Id", "Org", "Vdc", "DefaultGateway", "Dns1", "Dns2", "DnsSuffix", "FenceMode", "IsShared", "IsIpScopeInherited"}, data, networks)
 		},
 	}
+	addListFlags(cmd)
 	return cmd
 }
 
@@ -141,7 +165,11 @@ func NewCmdGetProviderGateway() *cobra.Command {
 		Aliases: []string{"gw"},
 		Run: func(cmd *cobra.Command, args []string) {
 			var data [][]string
-			for _, gw := range GetProviderGateways() {
+			gateways, err := QueryProviderGateways(listQuery())
+			if err != nil {
+				Fatal(err)
+			}
+			for _, gw := range gateways {
 				subnet := gw.Subnets.Values[0]
 				ipRanges := []string{}
 				for _, ipr := range subnet.IpRanges.Values {
@@ -156,33 +184,45 @@ func NewCmdGetProviderGateway() *cobra.Command {
 					strings.Join(ipRanges, ","),
 				})
 			}
-			PrityPrint([]string{"Name", "Id", "Tier0", "NetworkProvider", "Gateway", "IpRange"}, data)
+			PrintResult([]string{"Name", "Id", "Tier0", "NetworkProvider", "Gateway", "IpRange"}, data, gateways)
 		},
 	}
+	addListFlags(cmd)
 	return cmd
 }
 
 func NewCmdGetEdge() *cobra.Command {
 	cmd := &cobra.Command{
-		Use:     "edge ${VDC_NAME}",
+		Use:     "edge [${VDC_NAME}]",
 		Short:   "Get Edge [e]",
 		Aliases: []string{"e"},
-		Args:    cobra.ExactArgs(1),
+		Args:    cobra.MaximumNArgs(1),
 		ValidArgsFunction: func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
 			if len(args) != 0 {
 				return nil, cobra.ShellCompDirectiveNoFileComp
 			}
 			initClient()
-			return GetOvdcNames(), cobra.ShellCompDirectiveNoFileComp
+			return completeNames(GetOvdcNames())
 		},
 		Run: func(cmd *cobra.Command, args []string) {
-			vdcName := args[0]
+			vdcName := ""
+			if len(args) > 0 {
+				vdcName = args[0]
+			}
+			vdcName = orgVdcOrContext(vdcName)
+			if vdcName == "" {
+				Fatal("org vdc name not specified")
+			}
 			orgVdc, err := GetVdc(vdcName)
 			if err != nil {
 				Fatal(err)
 			}
 			var data [][]string
-			for _, edge := range GetEdges(vdcName) {
+			edges, err := QueryEdges(vdcName, listQuery())
+			if err != nil {
+				Fatal(err)
+			}
+			for _, edge := range edges {
 				data = append(data, []string{
 					edge.Name,
 					edge.Urn,
@@ -194,9 +234,10 @@ func NewCmdGetEdge() *cobra.Command {
 					strconv.Itoa(len(edge.EdgeGatewayUplinks)),
 				})
 			}
-			PrityPrint([]string{"Name", "Id", "Org", "Vdc", "Owner", "NetworkProvider", "EdgeCluster", "IfCount"}, data)
+			PrintResult([]string{"Name", "Id", "Org", "Vdc", "Owner", "NetworkProvider", "EdgeCluster", "IfCount"}, data, edges)
 		},
 	}
+	addListFlags(cmd)
 	return cmd
 }
 
@@ -208,7 +249,7 @@ func NewCmdGetEdgeNetwork() *cobra.Command {
 		Short:   "Get EdgeNetwork [en]",
 		Aliases: []string{"en"},
 		Run: func(cmd *cobra.Command, args []string) {
-			edge, err := GetEdge(edgeName, orgvdcName)
+			edge, err := GetEdge(edgeOrContext(edgeName), orgVdcOrContext(orgvdcName))
 			if err != nil {
 				Fatal(err)
 			}
@@ -227,19 +268,19 @@ func NewCmdGetEdgeNetwork() *cobra.Command {
 					fmt.Sprintf("%s/%d", subnet.GatewayAddress, subnet.PrefixLength),
 				})
 			}
-			PrityPrint([]string{"Name", "Id", "BackingType", "Dedicated", "Connected", "Vrf", "PrimaryIp", "GatewayAddress"}, data)
+			PrintResult([]string{"Name", "Id", "BackingType", "Dedicated", "Connected", "Vrf", "PrimaryIp", "GatewayAddress"}, data, edge.EdgeGatewayUplinks)
 		},
 	}
-	cmd.PersistentFlags().StringVarP(&orgvdcName, "orgvdc", "v", "", "org vdc name")
-	cmd.PersistentFlags().StringVarP(&edgeName, "edge", "e", "", "edge name")
+	cmd.PersistentFlags().StringVarP(&orgvdcName, "orgvdc", "v", "", "org vdc name (default: the org vdc of the context)")
+	cmd.PersistentFlags().StringVarP(&edgeName, "edge", "e", "", "edge name (default: the edge of the context)")
 
 	cmd.RegisterFlagCompletionFunc("orgvdc", func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
 		initClient()
-		return GetOvdcNames(), cobra.ShellCompDirectiveNoFileComp
+		return completeNames(GetOvdcNames())
 	})
 	cmd.RegisterFlagCompletionFunc("edge", func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
 		initClient()
-		return GetEdgeNames(orgvdcName), cobra.ShellCompDirectiveNoFileComp
+		return completeNames(GetEdgeNames(orgVdcOrContext(orgvdcName)))
 	})
 	return cmd
 }
@@ -256,7 +297,7 @@ func NewCmdGetVApp() *cobra.Command {
 				return nil, cobra.ShellCompDirectiveNoFileComp
 			}
 			initClient()
-			return GetVAppNames(), cobra.ShellCompDirectiveNoFileComp
+			return completeNames(GetVAppNames())
 		},
 		Run: func(cmd *cobra.Command, args []string) {
 			if len(args) > 0 {
@@ -265,6 +306,17 @@ func NewCmdGetVApp() *cobra.Command {
 				if err != nil {
 					Fatal(err)
 				}
+				if vapp.Status != "POWERED_OFF" {
+					lease, err := GetVAppLease(vapp.Id)
+					if err != nil {
+						Fatal(err)
+					}
+					vapp.LeaseExpiration = lease.DeploymentLeaseExpiration
+				}
+				if isStructuredOutput() {
+					PrintObject(vapp)
+					return
+				}
 				fmt.Println()
 				fmt.Printf("Id: %s\n", vapp.Id)
 				fmt.Printf("vAppName: %s\n", vapp.Name)
@@ -272,9 +324,8 @@ func NewCmdGetVApp() *cobra.Command {
 				fmt.Printf("VdcName: %s\n", vapp.VdcName)
 				fmt.Printf("Status: %s\n", vapp.Status)
 				fmt.Printf("NumOfVms: %d\n", vapp.NumberOfVMs)
-				if vapp.Status != "POWERED_OFF" {
-					lease := GetVAppLease(vapp.Id)
-					exp, err := time.Parse("2006-01-02T15:04:05.000Z", lease.DeploymentLeaseExpiration)
+				if vapp.LeaseExpiration != "" {
+					exp, err := time.Parse("2006-01-02T15:04:05.000Z", vapp.LeaseExpiration)
 					if err != nil {
 						Log(err.Error())
 					}
@@ -284,7 +335,33 @@ func NewCmdGetVApp() *cobra.Command {
 				return
 			}
 			var dataList [][]string
-			for _, vapp := range GetVApps() {
+			vapps, err := QueryVApps(listQuery())
+			if err != nil {
+				Fatal(err)
+			}
+
+			// the leases of the running vApps, fetched at once
+			leases := map[string]LeaseSettingsSection{}
+			if showlease {
+				vappIds := []string{}
+				for _, vapp := range vapps {
+					if vapp.Status != "POWERED_OFF" {
+						vappIds = append(vappIds, vapp.Id)
+					}
+				}
+				leaseList, err := GetVAppLeases(vappIds)
+				if err != nil {
+					Fatal(err)
+				}
+				for i, id := range vappIds {
+					leases[id] = leaseList[i]
+				}
+				for i := range vapps {
+					vapps[i].LeaseExpiration = leases[vapps[i].Id].DeploymentLeaseExpiration
+				}
+			}
+
+			for _, vapp := range vapps {
 				data := []string{
 					Truncate(vapp.Name, 42),
 					vapp.Id,
@@ -296,8 +373,7 @@ func NewCmdGetVApp() *cobra.Command {
 				}
 				if showlease {
 					exp_str := ""
-					if vapp.Status != "POWERED_OFF" {
-						lease := GetVAppLease(vapp.Id)
+					if lease, ok := leases[vapp.Id]; ok {
 						exp, err := time.Parse("2006-01-02T15:04:05.000Z", lease.DeploymentLeaseExpiration)
 						if err != nil {
 							Log(err.Error())
@@ -313,10 +389,11 @@ func NewCmdGetVApp() *cobra.Command {
 			if showlease {
 				header = append(header, "LeaseExpiration")
 			}
-			PrityPrint(header, dataList)
+			PrintResult(header, dataList, vapps)
 		},
 	}
 	cmd.PersistentFlags().BoolVarP(&showlease, "showlease", "l", false, "show lease info")
+	addListFlags(cmd)
 	return cmd
 }
 
@@ -331,7 +408,7 @@ func NewCmdGetVAppNetwork() *cobra.Command {
 				return nil, cobra.ShellCompDirectiveNoFileComp
 			}
 			initClient()
-			return GetVAppNames(), cobra.ShellCompDirectiveNoFileComp
+			return completeNames(GetVAppNames())
 		},
 		Run: func(cmd *cobra.Command, args []string) {
 			vapp, err := GetVAppByNameOrId(args[0], false)
@@ -342,10 +419,17 @@ func NewCmdGetVAppNetwork() *cobra.Command {
 			if err != nil {
 				Fatal(err)
 			}
-			vdcNetworks := GetOrgVdcNetworks(orgVdc.Id)
+			vdcNetworks, err := GetOrgVdcNetworks(orgVdc.Id)
+			if err != nil {
+				Fatal(err)
+			}
 
 			var data [][]string
-			for _, nw := range GetVAppNetwork(vapp.Id) {
+			networks, err := GetVAppNetwork(vapp.Id)
+			if err != nil {
+				Fatal(err)
+			}
+			for _, nw := range networks {
 				IpScope := nw.Configuration.IpScopes.IpScope[0]
 				var vdcNetwork OrgVdcNetwork
 				for _, vdcnw := range vdcNetworks {
@@ -362,7 +446,7 @@ func NewCmdGetVAppNetwork() *cobra.Command {
 					nw.Configuration.ParentNetwork.Id,
 					vdcNetwork.Configuration.FenceMode})
 			}
-			PrityPrint([]string{"Name", "IsInherited", "IsEnabled", "DefaultGateway", "ParentName", "ParentId", "ParentFenceMode"}, data)
+			PrintResult([]string{"Name", "IsInherited", "IsEnabled", "DefaultGateway", "ParentName", "ParentId", "ParentFenceMode"}, data, networks)
 		},
 	}
 	return cmd
@@ -379,7 +463,7 @@ func NewCmdGetVAppVm() *cobra.Command {
 				return nil, cobra.ShellCompDirectiveNoFileComp
 			}
 			initClient()
-			return GetVAppNames(), cobra.ShellCompDirectiveNoFileComp
+			return completeNames(GetVAppNames())
 		},
 		Run: func(cmd *cobra.Command, args []string) {
 			vapp, err := GetVAppByNameOrId(args[0], false)
@@ -388,13 +472,17 @@ func NewCmdGetVAppVm() *cobra.Command {
 			}
 
 			var data [][]string
-			for _, vm := range GetVAppVm(vapp.Id) {
+			vms, err := GetVAppVm(vapp.Id)
+			if err != nil {
+				Fatal(err)
+			}
+			for _, vm := range vms {
 				data = append(data, []string{
 					vm.Name,
 					vm.Urn,
 					vm.Href})
 			}
-			PrityPrint([]string{"Name", "Urn", "Href"}, data)
+			PrintResult([]string{"Name", "Urn", "Href"}, data, vms)
 		},
 	}
 	return cmd
@@ -411,7 +499,7 @@ func NewCmdGetVAppVmNetwork() *cobra.Command {
 				return nil, cobra.ShellCompDirectiveNoFileComp
 			}
 			initClient()
-			return GetVAppNames(), cobra.ShellCompDirectiveNoFileComp
+			return completeNames(GetVAppNames())
 		},
 		Run: func(cmd *cobra.Command, args []string) {
 			vapp, err := GetVAppByNameOrId(args[0], false)
@@ -420,7 +508,11 @@ func NewCmdGetVAppVmNetwork() *cobra.Command {
 			}
 
 			var data [][]string
-			for _, vm := range GetVAppVm(vapp.Id) {
+			vms, err := GetVAppVm(vapp.Id)
+			if err != nil {
+				Fatal(err)
+			}
+			for _, vm := range vms {
 				vnics := vm.NetworkConnectionSection.NetworkConnection
 				sort.Slice(vnics, func(i, j int) bool {
 					return vnics[i].NetworkConnectionIndex < vnics[j].NetworkConnectionIndex
@@ -438,7 +530,7 @@ func NewCmdGetVAppVmNetwork() *cobra.Command {
 						nw.MACAddress})
 				}
 			}
-			PrityPrint([]string{"Vm", "VmId", "Index", "IsConnected", "Type", "Network", "Mode", "IpAddress", "MacAddress"}, data)
+			PrintResult([]string{"Vm", "VmId", "Index", "IsConnected", "Type", "Network", "Mode", "IpAddress", "MacAddress"}, data, vms)
 		},
 	}
 	return cmd
@@ -458,39 +550,47 @@ func NewCmdGetTask() *cobra.Command {
 				return nil, cobra.ShellCompDirectiveNoFileComp
 			}
 			initClient()
-			orgs := GetOrgs()
-			orgNames := []string{}
-			for _, o := range orgs {
-				orgNames = append(orgNames, o.Name)
-			}
-
-			return orgNames, cobra.ShellCompDirectiveNoFileComp
+			return completeNames(GetOrgNames())
 		},
 		Run: func(cmd *cobra.Command, args []string) {
-			orgId := ""
-			orgName := client.site.OrgName
+			orgName := ""
 			if len(args) > 0 {
 				orgName = args[0]
 			}
+			orgName = orgOrContext(orgName)
 			if orgName == "" {
 				Fatal("org name not specified")
 			}
-			org := GetOrg(orgName)
-			orgId = org.Id
+			org, err := GetOrg(orgName)
+			if err != nil {
+				Fatal(err)
+			}
 
+			var tasks []Task
+			if latest || taskId == "" {
+				// the latest ones by default
+				q := listQuery()
+				if latest {
+					q.Limit = 1
+				} else if q.Limit == 0 {
+					q.Limit = 5
+				}
+				tasks, err = QueryTasks(org.Name, q)
+				if err != nil {
+					Fatal(err)
+				}
+			}
 			if latest {
-				task := GetTasks(orgId)[0]
-				taskId = LastOne(task.Href, "/")
+				if len(tasks) == 0 {
+					Fatal("no task found")
+				}
+				taskId = LastOne(tasks[0].Href, "/")
 			}
 
 			if taskId == "" {
 				header := []string{"Org", "Operation", "Id", "Status", "Object", "User", "Start", "End"}
-				maxcount := 5
-				if orgId == "" {
-					maxcount = 10
-				}
 				var data [][]string
-				for _, task := range GetTasks(orgId) {
+				for _, task := range tasks {
 					data = append(data, []string{
 						task.Org.Name,
 						task.Operation,
@@ -500,14 +600,17 @@ func NewCmdGetTask() *cobra.Command {
 						task.User.Name,
 						task.StartTime,
 						task.EndTime})
-					maxcount--
-					if maxcount == 0 {
-						break
-					}
 				}
-				PrityPrint(header, data)
+				PrintResult(header, data, tasks)
 			} else {
-				task := GetTask(taskId)
+				task, err := GetTask(taskId)
+				if err != nil {
+					Fatal(err)
+				}
+				if isStructuredOutput() {
+					PrintObject(task)
+					return
+				}
 				fmt.Println("Org: " + task.Org.Name)
 				fmt.Println("Operation: " + task.Operation)
 				fmt.Println("Status: " + task.Status)
@@ -541,48 +644,38 @@ func NewCmdGetTask() *cobra.Command {
 	}
 	cmd.PersistentFlags().StringVarP(&taskId, "id", "i", "", "task id")
 	cmd.PersistentFlags().BoolVarP(&latest, "latest", "l", false, "latest task")
+	addListFlags(cmd)
 	return cmd
 }
 
-func GetOrgs() []Org {
-	res := client.Request("GET", "/api/org", nil, nil)
-	var orgList OrgList
-	err := xml.Unmarshal(res.Body, &orgList)
+func GetOrgs() ([]Org, error) {
+	return QueryOrgs(Query{})
+}
+
+// QueryOrgs returns the orgs matching the query, of all the pages.
+func QueryOrgs(q Query) ([]Org, error) {
+	q.SortAsc = "name"
+	orgs, err := QueryAllRecords[Org]("organization", q)
 	if err != nil {
-		Fatal(err)
+		return nil, err
 	}
 
-	for i := 0; i < len(orgList.Org); i++ {
-		orgList.Org[i].Id = LastOne(orgList.Org[i].Href, "/")
+	for i := 0; i < len(orgs); i++ {
+		orgs[i].Id = LastOne(orgs[i].Href, "/")
 	}
-
-	orgs := orgList.Org
-	sort.Slice(orgs, func(i, j int) bool {
-		return orgs[i].Name < orgs[j].Name
-	})
-	return orgs
+	return orgs, nil
 }
 
-func GetOrgVdcs() []OrgVdc {
-	var vdcs []OrgVdc
-	var orgVdcList OrgVdcList
+func GetOrgVdcs() ([]OrgVdc, error) {
+	return QueryOrgVdcs(Query{})
+}
 
-	res := client.Request("GET", "/api/query?type=adminOrgVdc", nil, nil)
-	err := xml.Unmarshal(res.Body, &orgVdcList)
+// QueryOrgVdcs returns the org vdcs matching the query, of all the pages.
+func QueryOrgVdcs(q Query) ([]OrgVdc, error) {
+	q.SortAsc = "name"
+	vdcs, err := QueryAllRecords[OrgVdc]("adminOrgVdc", q)
 	if err != nil {
-		Fatal(err)
-	}
-	vdcs = append(vdcs, orgVdcList.OrgVdc...)
-
-	for orgVdcList.Total > orgVdcList.PageSize*orgVdcList.Page {
-		api := fmt.Sprintf("/api/query?type=adminOrgVdc&pageSize=%d&page=%d", orgVdcList.PageSize, orgVdcList.Page+1)
-		res := client.Request("GET", api, nil, nil)
-		orgVdcList = OrgVdcList{}
-		err := xml.Unmarshal(res.Body, &orgVdcList)
-		if err != nil {
-			Fatal(err)
-		}
-		vdcs = append(vdcs, orgVdcList.OrgVdc...)
+		return nil, err
 	}
 
 	for i := 0; i < len(vdcs); i++ {
@@ -592,77 +685,94 @@ func GetOrgVdcs() []OrgVdc {
 	sort.Slice(vdcs, func(i, j int) bool {
 		return vdcs[i].Name < vdcs[j].Name
 	})
-	return vdcs
+	return vdcs, nil
 }
 
-func GetOrgVdcNetworks(vdcId string) []OrgVdcNetwork {
-	res := client.Request("GET", "/api/admin/vdc/"+vdcId, nil, nil)
+// QueryOrgVdcNetworks returns the networks of the vdc matching the query,
+// of all the pages.
+func QueryOrgVdcNetworks(vdcHref string, q Query) ([]OrgVdcNetwork, error) {
+	q.Filter = andFilter(fiqlEq("vdc", vdcHref), q.Filter)
+	q.SortAsc = "name"
+	records, err := QueryAllRecords[Reference]("orgVdcNetwork", q)
+	if err != nil {
+		return nil, err
+	}
+	return parallelMap(records, func(record Reference) (OrgVdcNetwork, error) {
+		return getAdminNetwork(LastOne(record.Href, "/"))
+	})
+}
 
-	var adminVdc AdminVdc
-	err := xml.Unmarshal(res.Body, &adminVdc)
+func GetOrgVdcNetworks(vdcId string) ([]OrgVdcNetwork, error) {
+	res, err := client.Request("GET", "/api/admin/vdc/"+vdcId, nil, nil)
 	if err != nil {
-		Fatal(err)
+		return nil, err
 	}
 
-	var orgVdcNetworkList []OrgVdcNetwork
-	for i := 0; i < len(adminVdc.AvailableNetworks.Network); i++ {
-		networkId := LastOne(adminVdc.AvailableNetworks.Network[i].Href, "/")
-		res2 := client.Request("GET", "/api/admin/network/"+networkId, nil, nil)
+	var adminVdc AdminVdc
+	if err := xml.Unmarshal(res.Body, &adminVdc); err != nil {
+		return nil, responseError(res, err)
+	}
 
-		var orgVdcNetwork OrgVdcNetwork
-		err := xml.Unmarshal(res2.Body, &orgVdcNetwork)
-		if err != nil {
-			Fatal(err)
-		}
-		orgVdcNetwork.Id = LastOne(orgVdcNetwork.Href, "/")
-		orgVdcNetworkList = append(orgVdcNetworkList, orgVdcNetwork)
+	orgVdcNetworkList, err := parallelMap(adminVdc.AvailableNetworks.Network, func(network Reference) (OrgVdcNetwork, error) {
+		return getAdminNetwork(LastOne(network.Href, "/"))
+	})
+	if err != nil {
+		return nil, err
 	}
 
 	sort.Slice(orgVdcNetworkList, func(i, j int) bool {
 		return orgVdcNetworkList[i].Name < orgVdcNetworkList[j].Name
 	})
 
-	return orgVdcNetworkList
+	return orgVdcNetworkList, nil
 }
 
-func GetOrgVdcNetwork(name string, vdcId string) (OrgVdcNetworkJson, error) {
-	api := fmt.Sprintf("/cloudapi/1.0.0/orgVdcNetworks?filter=(name==%s;orgVdc.id==urn:vcloud:vdc:%s)", name, vdcId)
-	res := client.Request("GET", api, nil, nil)
+func getAdminNetwork(networkId string) (OrgVdcNetwork, error) {
+	res, err := client.Request("GET", "/api/admin/network/"+networkId, nil, nil)
+	if err != nil {
+		return OrgVdcNetwork{}, err
+	}
 
-	result := struct {
-		Values []OrgVdcNetworkJson `json:"values"`
-	}{}
-	err := json.Unmarshal(res.Body, &result)
+	var orgVdcNetwork OrgVdcNetwork
+	if err := xml.Unmarshal(res.Body, &orgVdcNetwork); err != nil {
+		return OrgVdcNetwork{}, responseError(res, err)
+	}
+	orgVdcNetwork.Id = LastOne(orgVdcNetwork.Href, "/")
+	return orgVdcNetwork, nil
+}
+
+func GetOrgVdcNetwork(name string, vdcId string) (OrgVdcNetworkJson, error) {
+	q := Query{Filter: andFilter(fiqlEq("name", name), fiqlEq("orgVdc.id", "urn:vcloud:vdc:"+vdcId))}
+	networks, err := QueryAllCloudApi[OrgVdcNetworkJson]("/cloudapi/1.0.0/orgVdcNetworks", q)
 	if err != nil {
-		Fatal(err)
+		return OrgVdcNetworkJson{}, err
 	}
-	if len(result.Values) == 0 {
-		return OrgVdcNetworkJson{}, fmt.Errorf("orgvds network [%s] not found", name)
+	if len(networks) == 0 {
+		return OrgVdcNetworkJson{}, &NotFoundError{Kind: "orgvdc network", Name: name}
 	}
-	if len(result.Values) != 1 {
-		Fatal(fmt.Sprintf("result count is %d, expected is 1", len(result.Values)))
+	if len(networks) != 1 {
+		return OrgVdcNetworkJson{}, fmt.Errorf("result count is %d, expected is 1", len(networks))
 	}
 
-	return result.Values[0], nil
+	return networks[0], nil
 }
 
-func GetVApps() []VApp {
-	vapps := []VApp{}
-	page := 1
-	for {
-		res := client.Request("GET", fmt.Sprintf("/api/vApps/query?page=%d", page), nil, nil)
-		var vappList VAppList
-		if err := xml.Unmarshal(res.Body, &vappList); err != nil {
-			Fatal(err)
-		}
-		vapps = append(vapps, vappList.VApp...)
-		if vappList.Total <= vappList.Page*vappList.PageSize {
-			break
-		}
-		page++
+func GetVApps() ([]VApp, error) {
+	return QueryVApps(Query{})
+}
+
+// QueryVApps returns the vApps matching the query, of all the pages.
+func QueryVApps(q Query) ([]VApp, error) {
+	q.SortAsc = "name"
+	vapps, err := QueryAllRecords[VApp]("vApp", q)
+	if err != nil {
+		return nil, err
 	}
 
-	var orgList []Org = GetOrgs()
+	orgList, err := GetOrgs()
+	if err != nil {
+		return nil, err
+	}
 
 	for i := 0; i < len(vapps); i++ {
 		vapps[i].Id = LastOne(vapps[i].Href, "/")
@@ -678,44 +788,60 @@ func GetVApps() []VApp {
 		return vapps[i].Name < vapps[j].Name
 	})
 
-	return vapps
+	return vapps, nil
 }
 
-func GetVdcNetworkType(networkId string) string {
-	res := client.Request("GET", "/api/network/"+networkId, nil, nil)
+func GetVdcNetworkType(networkId string) (string, error) {
+	res, err := client.Request("GET", "/api/network/"+networkId, nil, nil)
+	if err != nil {
+		return "", err
+	}
 
 	var network Network
-	err := xml.Unmarshal(res.Body, &network)
-	if err != nil {
-		Fatal(err)
+	if err := xml.Unmarshal(res.Body, &network); err != nil {
+		return "", responseError(res, err)
 	}
 
-	return network.Configuration.FenceMode
+	return network.Configuration.FenceMode, nil
 }
 
-func GetTasks(orgId string) []Task {
+func GetTasks(orgId string) ([]Task, error) {
 	tasks := []Task{}
 
 	orgIdList := []string{}
 	if orgId != "" {
 		orgIdList = append(orgIdList, orgId)
-		system := GetOrg("System")
+		system, err := GetOrg("System")
+		if err != nil {
+			return nil, err
+		}
 		orgIdList = append(orgIdList, system.Id)
 	} else {
-		for _, org := range GetOrgs() {
+		orgs, err := GetOrgs()
+		if err != nil {
+			return nil, err
+		}
+		for _, org := range orgs {
 			orgIdList = append(orgIdList, org.Id)
 		}
 	}
 
-	for _, id := range orgIdList {
-		res := client.Request("GET", "/api/tasksList/"+id, nil, nil)
-
-		var taskList TaskList
-		err := xml.Unmarshal(res.Body, &taskList)
+	taskLists, err := parallelMap(orgIdList, func(id string) (TaskList, error) {
+		res, err := client.Request("GET", "/api/tasksList/"+id, nil, nil)
 		if err != nil {
-			Fatal(err)
+			return TaskList{}, err
 		}
 
+		var taskList TaskList
+		if err := xml.Unmarshal(res.Body, &taskList); err != nil {
+			return TaskList{}, responseError(res, err)
+		}
+		return taskList, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	for _, taskList := range taskLists {
 		tasks = append(tasks, taskList.Task...)
 	}
 
@@ -723,67 +849,139 @@ func GetTasks(orgId string) []Task {
 		return tasks[i].StartTime > tasks[j].StartTime
 	})
 
-	return tasks
+	return tasks, nil
+}
+
+// QueryTasks returns the tasks of the org and of System matching the query,
+// the latest first.
+func QueryTasks(orgName string, q Query) ([]Task, error) {
+	q.Filter = andFilter(fiqlEq("orgName", orgName)+","+fiqlEq("orgName", "System"), q.Filter)
+	q.SortDesc = "startDate"
+	records, err := QueryAllRecords[TaskRecord]("adminTask", q)
+	if err != nil {
+		return nil, err
+	}
+	tasks := []Task{}
+	for _, record := range records {
+		tasks = append(tasks, record.Task())
+	}
+	return tasks, nil
 }
 
-func GetTask(taskId string) Task {
-	res := client.Request("GET", "/api/task/"+taskId, nil, nil)
+func GetTask(taskId string) (Task, error) {
+	res, err := client.Request("GET", "/api/task/"+taskId, nil, nil)
+	if err != nil {
+		return Task{}, err
+	}
 
 	var task Task
-	err := xml.Unmarshal(res.Body, &task)
-	if err != nil {
-		Fatal(err)
+	if err := xml.Unmarshal(res.Body, &task); err != nil {
+		return Task{}, responseError(res, err)
 	}
 
-	return task
+	return task, nil
 }
 
-func GetOrg(orgName string) Org {
-	res := client.Request("GET", fmt.Sprintf("/api/admin/orgs/query?filter=(name==%s)", orgName), nil, nil)
-
+func GetOrg(orgName string) (Org, error) {
 	var orgResults struct {
-		OrgRecord Org `xml:"OrgRecord"`
+		OrgRecord *Org `xml:"OrgRecord"`
 	}
-	err := xml.Unmarshal(res.Body, &orgResults)
-	if err != nil {
-		Fatal(err)
+	if err := QueryRecords("organization", Query{Filter: fiqlEq("name", orgName)}, &orgResults); err != nil {
+		return Org{}, err
+	}
+	if orgResults.OrgRecord == nil {
+		return Org{}, &NotFoundError{Kind: "org", Name: orgName}
 	}
 	orgResults.OrgRecord.Id = LastOne(orgResults.OrgRecord.Href, "/")
 
-	return orgResults.OrgRecord
+	return *orgResults.OrgRecord, nil
 }
 
-func GetVdc(vdcName string) (OrgVdc, error) {
-	for _, vdc := range GetOrgVdcs() {
-		if vdc.Name == vdcName {
-			return vdc, nil
-		}
+// GetVdc finds the vdc by the name, which may be given as
+// "${ORG_NAME}/${VDC_NAME}", and must be when the name alone is ambiguous.
+// The query asks for two records to tell an ambiguous name.
+func GetVdc(name string) (OrgVdc, error) {
+	filter := fiqlEq("name", name)
+	orgName, vdcName, qualified := strings.Cut(name, "/")
+	if qualified {
+		filter = andFilter(fiqlEq("orgName", orgName), fiqlEq("name", vdcName))
+	} else {
+		orgName, vdcName = "", name
 	}
-	return OrgVdc{}, fmt.Errorf("Org VDC \"" + vdcName + "\" not found")
+	var orgVdcList OrgVdcList
+	if err := QueryRecords("adminOrgVdc", Query{Filter: filter, PageSize: 2}, &orgVdcList); err != nil {
+		return OrgVdc{}, err
+	}
+	switch {
+	case len(orgVdcList.OrgVdc) == 0:
+		return OrgVdc{}, &NotFoundError{Kind: "Org VDC", Name: vdcName, Parent: orgName}
+	case len(orgVdcList.OrgVdc) > 1 && qualified:
+		return OrgVdc{}, fmt.Errorf("Org VDC name '%s' is ambiguous in the org '%s'", vdcName, orgName)
+	case len(orgVdcList.OrgVdc) > 1:
+		return OrgVdc{}, fmt.Errorf("Org VDC name '%s' is ambiguous, give the org as ${ORG_NAME}/%s", name, name)
+	}
+	vdc := orgVdcList.OrgVdc[0]
+	vdc.Id = LastOne(vdc.Href, "/")
+	return vdc, nil
 }
 
+// GetVAppByNameOrId finds the vApp by the name (or a part of it when
+// partialSearch, the first one by name), or by the id.
 func GetVAppByNameOrId(vappName string, partialSearch bool) (VApp, error) {
-	for _, vapp := range GetVApps() {
-		if partialSearch {
-			if strings.Contains(vapp.Name, vappName) {
-				return vapp, nil
-			}
-		} else {
-			if vapp.Name == vappName || vapp.Id == vappName {
-				return vapp, nil
-			}
+	filter := fiqlEq("name", vappName)
+	if partialSearch {
+		filter = "name==*" + fiqlEscape(vappName) + "*"
+	}
+	vapps, err := QueryAllRecords[VApp]("vApp", Query{Filter: filter, SortAsc: "name", Limit: 1})
+	if err != nil {
+		return VApp{}, err
+	}
+	if len(vapps) == 0 && !partialSearch {
+		// by the id, vapp-<uuid> or the uuid. Not by the href, the endpoint of
+		// the site may not be the address vCD puts in the hrefs.
+		urn := "urn:vcloud:vapp:" + strings.TrimPrefix(vappName, "vapp-")
+		vapps, err = QueryAllRecords[VApp]("vApp", Query{Filter: fiqlEq("id", urn), Limit: 1})
+		if err != nil {
+			return VApp{}, err
 		}
 	}
-	return VApp{}, fmt.Errorf("vApp \"%s\" not found", vappName)
+	if len(vapps) == 0 {
+		return VApp{}, &NotFoundError{Kind: "vApp", Name: vappName}
+	}
+
+	vapp := vapps[0]
+	vapp.Id = LastOne(vapp.Href, "/")
+	org, err := GetOrgByHref(vapp.OrgHref)
+	if err != nil {
+		return VApp{}, err
+	}
+	vapp.OrgName = org.Name
+	return vapp, nil
 }
 
-func GetVAppNetwork(vappId string) []Network {
-	res := client.Request("GET", "/api/vApp/"+vappId+"/networkConfigSection", nil, nil)
+// GetOrgByHref gets the org by the href in a record, e.g. the org of a vApp.
+func GetOrgByHref(href string) (Org, error) {
+	res, err := client.Request("GET", "/api/org/"+LastOne(href, "/"), nil, nil)
+	if err != nil {
+		return Org{}, err
+	}
+	var org Org
+	if err := xml.Unmarshal(res.Body, &org); err != nil {
+		return Org{}, responseError(res, err)
+	}
+	org.Id = LastOne(org.Href, "/")
+	return org, nil
+}
 
-	var networkConfigSection NetworkConfigSection
-	err := xml.Unmarshal(res.Body, &networkConfigSection)
+func GetVAppNetwork(vappId string) ([]Network, error) {
+	res, err := client.Request("GET", "/api/vApp/"+vappId+"/networkConfigSection", nil, nil)
 	if err != nil {
-		Fatal(err)
+		return nil, err
+	}
+
+	var networkConfigSection NetworkConfigSection
+	if err := xml.Unmarshal(res.Body, &networkConfigSection); err != nil {
+		return nil, responseError(res, err)
 	}
 
 	nws := networkConfigSection.NetworkConfig
@@ -791,16 +989,18 @@ func GetVAppNetwork(vappId string) []Network {
 		return nws[i].Name < nws[j].Name
 	})
 
-	return nws
+	return nws, nil
 }
 
-func GetVAppVm(vappId string) []VM {
-	res := client.Request("GET", "/api/vApp/"+vappId, nil, nil)
+func GetVAppVm(vappId string) ([]VM, error) {
+	res, err := client.Request("GET", "/api/vApp/"+vappId, nil, nil)
+	if err != nil {
+		return nil, err
+	}
 
 	var vappDetails VAppDetails
-	err := xml.Unmarshal(res.Body, &vappDetails)
-	if err != nil {
-		Fatal(err)
+	if err := xml.Unmarshal(res.Body, &vappDetails); err != nil {
+		return nil, responseError(res, err)
 	}
 
 	vms := vappDetails.VMs.VM
@@ -808,34 +1008,42 @@ func GetVAppVm(vappId string) []VM {
 		return vms[i].Name < vms[j].Name
 	})
 
-	return vms
+	return vms, nil
 }
 
-func GetVAppLease(vappId string) LeaseSettingsSection {
-	res := client.Request("GET", fmt.Sprintf("/api/vApp/%s/leaseSettingsSection", vappId), nil, nil)
+func GetVAppLease(vappId string) (LeaseSettingsSection, error) {
+	res, err := client.Request("GET", fmt.Sprintf("/api/vApp/%s/leaseSettingsSection", vappId), nil, nil)
+	if err != nil {
+		return LeaseSettingsSection{}, err
+	}
 
 	var vappLease LeaseSettingsSection
-	err := xml.Unmarshal(res.Body, &vappLease)
-	if err != nil {
-		Fatal(err)
+	if err := xml.Unmarshal(res.Body, &vappLease); err != nil {
+		return LeaseSettingsSection{}, responseError(res, err)
 	}
 
-	return vappLease
+	return vappLease, nil
 }
 
-func GetProviderVdc(name string) (Reference, error) {
-	res := client.Request("GET", fmt.Sprintf("/api/admin/extension/providerVdcReferences/query?filter=(name==%s)&sortAsc=name", name), nil, nil)
+// GetVAppLeases gets the leases of the vApps concurrently, in the same order.
+func GetVAppLeases(vappIds []string) ([]LeaseSettingsSection, error) {
+	return parallelMap(vappIds, GetVAppLease)
+}
 
+func GetProviderVdc(name string) (Reference, error) {
 	result := struct {
-		VMWProviderVdcRecord struct {
+		VMWProviderVdcRecord *struct {
 			Name string `xml:"name,attr"`
 			Href string `xml:"href,attr"`
 		} `xml:"VMWProviderVdcRecord"`
 	}{}
-	err := xml.Unmarshal(res.Body, &result)
-	if err != nil {
+	q := Query{Filter: fiqlEq("name", name), Fields: []string{"name"}}
+	if err := QueryRecords("providerVdc", q, &result); err != nil {
 		return Reference{}, err
 	}
+	if result.VMWProviderVdcRecord == nil {
+		return Reference{}, &NotFoundError{Kind: "provider vdc", Name: name}
+	}
 
 	return Reference{
 		Name: result.VMWProviderVdcRecord.Name,
@@ -845,18 +1053,19 @@ func GetProviderVdc(name string) (Reference, error) {
 }
 
 func GetNetworkPool(name string) (Reference, error) {
-	res := client.Request("GET", fmt.Sprintf("/api/admin/extension/networkPoolReferences/query?filter=(name==%s)", name), nil, nil)
-
 	result := struct {
-		NetworkPoolRecord struct {
+		NetworkPoolRecord *struct {
 			Name string `xml:"name,attr"`
 			Href string `xml:"href,attr"`
 		} `xml:"NetworkPoolRecord"`
 	}{}
-	err := xml.Unmarshal(res.Body, &result)
-	if err != nil {
+	q := Query{Filter: fiqlEq("name", name), Fields: []string{"name"}}
+	if err := QueryRecords("networkPool", q, &result); err != nil {
 		return Reference{}, err
 	}
+	if result.NetworkPoolRecord == nil {
+		return Reference{}, &NotFoundError{Kind: "network pool", Name: name}
+	}
 
 	return Reference{
 		Name: result.NetworkPoolRecord.Name,
@@ -866,157 +1075,159 @@ func GetNetworkPool(name string) (Reference, error) {
 }
 
 func GetStorageProfile(name string, providerVdcName string) (Reference, error) {
-	api := fmt.Sprintf("/cloudapi/1.0.0/pvdcStoragePolicies?filter=(name==%s;providerVdcRef.name==%s)", name, providerVdcName)
-	res := client.Request("GET", api, nil, nil)
-
-	result := struct {
-		Values []struct {
-			Urn  string `json:"id"`
-			Name string `json:"name"`
-		} `json:"values"`
-	}{}
-	err := json.Unmarshal(res.Body, &result)
+	q := Query{Filter: andFilter(fiqlEq("name", name), fiqlEq("providerVdcRef.name", providerVdcName))}
+	policies, err := QueryAllCloudApi[ReferenceJson]("/cloudapi/1.0.0/pvdcStoragePolicies", q)
 	if err != nil {
-		Fatal(err)
+		return Reference{}, err
 	}
-	if len(result.Values) == 0 {
-		return Reference{}, fmt.Errorf("storage profile [%s] not found at %s", name, providerVdcName)
+	if len(policies) == 0 {
+		return Reference{}, &NotFoundError{Kind: "storage profile", Name: name, Parent: providerVdcName}
 	}
-	if len(result.Values) != 1 {
-		Fatal(fmt.Sprintf("result count is %d, expected is 1", len(result.Values)))
+	if len(policies) != 1 {
+		return Reference{}, fmt.Errorf("result count is %d, expected is 1", len(policies))
 	}
 
 	return Reference{
-		Name: result.Values[0].Name,
-		Id:   result.Values[0].Urn,
+		Name: policies[0].Name,
+		Id:   policies[0].Urn,
 	}, nil
 }
 
 func GetEdge(name string, orgvdcName string) (EdgeGateway, error) {
-	api := fmt.Sprintf("/cloudapi/1.0.0/edgeGateways?filter=(name==%s;orgVdc.name==%s)", name, orgvdcName)
-	res := client.Request("GET", api, nil, nil)
-
-	result := struct {
-		Values []EdgeGateway `json:"values"`
-	}{}
-	err := json.Unmarshal(res.Body, &result)
+	q := Query{Filter: andFilter(fiqlEq("name", name), fiqlEq("orgVdc.name", orgvdcName))}
+	edges, err := QueryAllCloudApi[EdgeGateway]("/cloudapi/1.0.0/edgeGateways", q)
 	if err != nil {
-		Fatal(err)
+		return EdgeGateway{}, err
 	}
-	if len(result.Values) == 0 {
-		return EdgeGateway{}, fmt.Errorf("edge %s not found at %s", name, orgvdcName)
+	if len(edges) == 0 {
+		return EdgeGateway{}, &NotFoundError{Kind: "edge", Name: name, Parent: orgvdcName}
 	}
-	if len(result.Values) != 1 {
-		Fatal(fmt.Sprintf("result count is [%d], expected is 1", len(result.Values)))
+	if len(edges) != 1 {
+		return EdgeGateway{}, fmt.Errorf("result count is [%d], expected is 1", len(edges))
 	}
 
-	return result.Values[0], nil
+	return edges[0], nil
 }
 
-func GetEdges(orgvdcName string) []EdgeGateway {
-	api := fmt.Sprintf("/cloudapi/1.0.0/edgeGateways?filter=(orgVdc.name==%s)&sortAsc=name", orgvdcName)
-	res := client.Request("GET", api, nil, nil)
+func GetEdges(orgvdcName string) ([]EdgeGateway, error) {
+	return QueryEdges(orgvdcName, Query{})
+}
 
-	result := struct {
-		Values []EdgeGateway `json:"values"`
-	}{}
-	err := json.Unmarshal(res.Body, &result)
-	if err != nil {
-		Fatal(err)
-	}
-	return result.Values
+// QueryEdges returns the edges of the org vdc matching the query, of all the pages.
+func QueryEdges(orgvdcName string, q Query) ([]EdgeGateway, error) {
+	q.Filter = andFilter(fiqlEq("orgVdc.name", orgvdcName), q.Filter)
+	q.SortAsc = "name"
+	return QueryAllCloudApi[EdgeGateway]("/cloudapi/1.0.0/edgeGateways", q)
 }
 
 func GetExternalNetwork(name string) (ReferenceJson, error) {
-	api := fmt.Sprintf("/cloudapi/1.0.0/externalNetworks?filter=(name==%s)", name)
-	res := client.Request("GET", api, nil, nil)
-
-	result := struct {
-		Values []ReferenceJson `json:"values"`
-	}{}
-	err := json.Unmarshal(res.Body, &result)
+	networks, err := QueryAllCloudApi[ReferenceJson]("/cloudapi/1.0.0/externalNetworks", Query{Filter: fiqlEq("name", name)})
 	if err != nil {
-		Fatal(err)
+		return ReferenceJson{}, err
 	}
-	if len(result.Values) == 0 {
-		return ReferenceJson{}, fmt.Errorf("external network %s not found", name)
+	if len(networks) == 0 {
+		return ReferenceJson{}, &NotFoundError{Kind: "external network", Name: name}
 	}
-	if len(result.Values) != 1 {
-		Fatal(fmt.Sprintf("result count is [%d], expected is 1", len(result.Values)))
+	if len(networks) != 1 {
+		return ReferenceJson{}, fmt.Errorf("result count is [%d], expected is 1", len(networks))
 	}
 
-	return result.Values[0], nil
+	return networks[0], nil
 }
 
-func GetExternalNetworks() []ReferenceJson {
-	res := client.Request("GET", "/cloudapi/1.0.0/externalNetworks", nil, nil)
-
-	result := struct {
-		Values []ReferenceJson `json:"values"`
-	}{}
-	err := json.Unmarshal(res.Body, &result)
-	if err != nil {
-		Fatal(err)
-	}
-
-	return result.Values
+func GetExternalNetworks() ([]ReferenceJson, error) {
+	return QueryAllCloudApi[ReferenceJson]("/cloudapi/1.0.0/externalNetworks", Query{})
 }
 
 func GetProviderGateway(name string) (ProviderGateway, error) {
-	api := fmt.Sprintf("/cloudapi/1.0.0/externalNetworks?filter=(networkBackings.values.backingTypeValue==NSXT_TIER0;name==%s)", name)
-	res := client.Request("GET", api, nil, nil)
-
-	result := struct {
-		Values []ProviderGateway `json:"values"`
-	}{}
-	err := json.Unmarshal(res.Body, &result)
+	q := Query{Filter: andFilter(providerGatewayFilter, fiqlEq("name", name))}
+	gateways, err := QueryAllCloudApi[ProviderGateway]("/cloudapi/1.0.0/externalNetworks", q)
 	if err != nil {
-		Fatal(err)
+		return ProviderGateway{}, err
 	}
-	if len(result.Values) == 0 {
-		return ProviderGateway{}, fmt.Errorf("provider gateway [%s] not found", name)
+	if len(gateways) == 0 {
+		return ProviderGateway{}, &NotFoundError{Kind: "provider gateway", Name: name}
 	}
-	if len(result.Values) != 1 {
-		Fatal(fmt.Sprintf("result count is %d, expected is 1", len(result.Values)))
+	if len(gateways) != 1 {
+		return ProviderGateway{}, fmt.Errorf("result count is %d, expected is 1", len(gateways))
 	}
 
-	return result.Values[0], nil
+	return gateways[0], nil
 }
 
-func GetProviderGateways() []ProviderGateway {
-	api := "/cloudapi/1.0.0/externalNetworks?filter=(networkBackings.values.backingTypeValue==NSXT_TIER0)&sortAsc=name"
-	res := client.Request("GET", api, nil, nil)
+// the external networks backed by a tier0 or a VRF of a tier0 are the provider gateways
+const providerGatewayFilter = "networkBackings.values.backingTypeValue==NSXT_TIER0,networkBackings.values.backingTypeValue==NSXT_VRF_TIER0"
 
-	result := struct {
-		Values []ProviderGateway `json:"values"`
-	}{}
-	err := json.Unmarshal(res.Body, &result)
-	if err != nil {
-		Fatal(err)
+// BackingType returns the backing of the provider gateway, NSXT_TIER0 or
+// NSXT_VRF_TIER0 for a VRF-lite backed one.
+func (g ProviderGateway) BackingType() string {
+	for _, backing := range g.NetworkBackings.Values {
+		backingType := backing.BackingTypeValue
+		if backingType == "" {
+			backingType = backing.BackingType
+		}
+		if backingType == "NSXT_TIER0" || backingType == "NSXT_VRF_TIER0" {
+			return backingType
+		}
 	}
-	return result.Values
+	return "NSXT_TIER0"
+}
+
+func GetProviderGateways() ([]ProviderGateway, error) {
+	return QueryProviderGateways(Query{})
 }
 
-func GetOvdcNames() []string {
+// QueryProviderGateways returns the provider gateways matching the query, of all the pages.
+func QueryProviderGateways(q Query) ([]ProviderGateway, error) {
+	q.Filter = andFilter(providerGatewayFilter, q.Filter)
+	q.SortAsc = "name"
+	return QueryAllCloudApi[ProviderGateway]("/cloudapi/1.0.0/externalNetworks", q)
+}
+
+func GetOvdcNames() ([]string, error) {
+	vdcs, err := GetOrgVdcs()
+	if err != nil {
+		return nil, err
+	}
 	orgvdcNames := []string{}
-	for _, vdc := range GetOrgVdcs() {
+	for _, vdc := range vdcs {
 		orgvdcNames = append(orgvdcNames, vdc.Name)
 	}
-	return orgvdcNames
+	return orgvdcNames, nil
 }
 
-func GetEdgeNames(orgvdcName string) []string {
+func GetEdgeNames(orgvdcName string) ([]string, error) {
+	edges, err := GetEdges(orgvdcName)
+	if err != nil {
+		return nil, err
+	}
 	edgeNames := []string{}
-	for _, edge := range GetEdges(orgvdcName) {
+	for _, edge := range edges {
 		edgeNames = append(edgeNames, edge.Name)
 	}
-	return edgeNames
+	return edgeNames, nil
 }
 
-func GetVAppNames() []string {
+func GetVAppNames() ([]string, error) {
+	vapps, err := GetVApps()
+	if err != nil {
+		return nil, err
+	}
 	vappNames := []string{}
-	for _, vapp := range GetVApps() {
+	for _, vapp := range vapps {
 		vappNames = append(vappNames, vapp.Name)
 	}
-	return vappNames
+	return vappNames, nil
+}
+
+func GetOrgNames() ([]string, error) {
+	orgs, err := GetOrgs()
+	if err != nil {
+		return nil, err
+	}
+	orgNames := []string{}
+	for _, org := range orgs {
+		orgNames = append(orgNames, org.Name)
+	}
+	return orgNames, nil
 }
@@ -0,0 +1,494 @@
+package module
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// JSONPath is a small implementation of the kubectl flavored JSONPath
+// template ("{.items[*].name}"). It is evaluated against the json
+// representation of the objects, so the field names are the json names.
+//
+// Supported syntax:
+//
+//	{.a.b}  {.a[0]}  {.a[-1]}  {.a[1:3]}  {.a[*]}  {.a.*}  {..name}
+//	{.a[?(@.b=="x")]}  {range .items[*]}...{end}  {"literal"}
+type JSONPath struct {
+	nodes []jsonPathNode
+}
+
+type jsonPathNode struct {
+	kind  string // text, expr, range, end
+	text  string
+	steps []jsonPathStep
+}
+
+type jsonPathStep struct {
+	kind   string // field, recursive, wildcard, index, slice, filter
+	name   string
+	index  int
+	start  *int
+	end    *int
+	filter *jsonPathFilter
+}
+
+type jsonPathFilter struct {
+	steps []jsonPathStep
+	op    string
+	value any
+}
+
+func ParseJSONPath(template string) (*JSONPath, error) {
+	jp := &JSONPath{}
+	rest := template
+	for rest != "" {
+		open := strings.Index(rest, "{")
+		if open < 0 {
+			jp.nodes = append(jp.nodes, jsonPathNode{kind: "text", text: rest})
+			break
+		}
+		if open > 0 {
+			jp.nodes = append(jp.nodes, jsonPathNode{kind: "text", text: rest[:open]})
+		}
+		close := matchingBrace(rest, open)
+		if close < 0 {
+			return nil, fmt.Errorf("jsonpath: unclosed '{' in %q", template)
+		}
+		node, err := parseJSONPathNode(strings.TrimSpace(rest[open+1 : close]))
+		if err != nil {
+			return nil, err
+		}
+		jp.nodes = append(jp.nodes, node)
+		rest = rest[close+1:]
+	}
+	return jp, nil
+}
+
+func matchingBrace(s string, open int) int {
+	depth := 0
+	var quote byte
+	for i := open; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case quote != 0:
+			if c == '\\' {
+				i++
+			} else if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == '{':
+			depth++
+		case c == '}':
+			depth--
+			if depth == 0 {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+func parseJSONPathNode(expr string) (jsonPathNode, error) {
+	switch {
+	case expr == "end":
+		return jsonPathNode{kind: "end"}, nil
+	case strings.HasPrefix(expr, "range "):
+		steps, err := parseJSONPathSteps(strings.TrimSpace(strings.TrimPrefix(expr, "range ")))
+		return jsonPathNode{kind: "range", steps: steps}, err
+	case strings.HasPrefix(expr, "\""):
+		text, err := strconv.Unquote(expr)
+		if err != nil {
+			return jsonPathNode{}, fmt.Errorf("jsonpath: invalid literal %s", expr)
+		}
+		return jsonPathNode{kind: "text", text: text}, nil
+	default:
+		steps, err := parseJSONPathSteps(expr)
+		return jsonPathNode{kind: "expr", steps: steps}, err
+	}
+}
+
+func parseJSONPathSteps(expr string) ([]jsonPathStep, error) {
+	steps := []jsonPathStep{}
+	p := strings.TrimPrefix(strings.TrimPrefix(expr, "$"), "@")
+	for p != "" {
+		switch {
+		case strings.HasPrefix(p, ".."):
+			name, rest := readJSONPathName(p[2:])
+			steps = append(steps, jsonPathStep{kind: "recursive"})
+			if name != "" && name != "*" {
+				steps = append(steps, jsonPathStep{kind: "field", name: name})
+			} else if name == "*" {
+				steps = append(steps, jsonPathStep{kind: "wildcard"})
+			}
+			p = rest
+		case strings.HasPrefix(p, "."):
+			name, rest := readJSONPathName(p[1:])
+			if name == "*" {
+				steps = append(steps, jsonPathStep{kind: "wildcard"})
+			} else if name != "" {
+				steps = append(steps, jsonPathStep{kind: "field", name: name})
+			}
+			p = rest
+		case strings.HasPrefix(p, "["):
+			close := matchingBracket(p)
+			if close < 0 {
+				return nil, fmt.Errorf("jsonpath: unclosed '[' in %q", expr)
+			}
+			step, err := parseJSONPathBracket(strings.TrimSpace(p[1:close]))
+			if err != nil {
+				return nil, err
+			}
+			steps = append(steps, step)
+			p = p[close+1:]
+		default:
+			name, rest := readJSONPathName(p)
+			if name == "" {
+				return nil, fmt.Errorf("jsonpath: unexpected %q in %q", p, expr)
+			}
+			steps = append(steps, jsonPathStep{kind: "field", name: name})
+			p = rest
+		}
+	}
+	return steps, nil
+}
+
+func readJSONPathName(s string) (string, string) {
+	i := strings.IndexAny(s, ".[")
+	if i < 0 {
+		return s, ""
+	}
+	return s[:i], s[i:]
+}
+
+func matchingBracket(s string) int {
+	depth := 0
+	var quote byte
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == '[':
+			depth++
+		case c == ']':
+			depth--
+			if depth == 0 {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+func parseJSONPathBracket(s string) (jsonPathStep, error) {
+	switch {
+	case s == "*":
+		return jsonPathStep{kind: "wildcard"}, nil
+	case strings.HasPrefix(s, "'") || strings.HasPrefix(s, "\""):
+		return jsonPathStep{kind: "field", name: strings.Trim(s, "'\"")}, nil
+	case strings.HasPrefix(s, "?(") && strings.HasSuffix(s, ")"):
+		filter, err := parseJSONPathFilter(s[2 : len(s)-1])
+		return jsonPathStep{kind: "filter", filter: filter}, err
+	case strings.Contains(s, ":"):
+		step := jsonPathStep{kind: "slice"}
+		parts := strings.SplitN(s, ":", 2)
+		for i, part := range parts {
+			part = strings.TrimSpace(part)
+			if part == "" {
+				continue
+			}
+			n, err := strconv.Atoi(part)
+			if err != nil {
+				return step, fmt.Errorf("jsonpath: invalid slice [%s]", s)
+			}
+			if i == 0 {
+				step.start = &n
+			} else {
+				step.end = &n
+			}
+		}
+		return step, nil
+	default:
+		n, err := strconv.Atoi(s)
+		if err != nil {
+			return jsonPathStep{}, fmt.Errorf("jsonpath: invalid index [%s]", s)
+		}
+		return jsonPathStep{kind: "index", index: n}, nil
+	}
+}
+
+func parseJSONPathFilter(s string) (*jsonPathFilter, error) {
+	i, op := findJSONPathOperator(s)
+	if i < 0 {
+		// existence filter: [?(@.name)]
+		steps, err := parseJSONPathSteps(strings.TrimSpace(s))
+		return &jsonPathFilter{steps: steps}, err
+	}
+	steps, err := parseJSONPathSteps(strings.TrimSpace(s[:i]))
+	if err != nil {
+		return nil, err
+	}
+	var value any
+	literal := strings.TrimSpace(s[i+len(op):])
+	if len(literal) >= 2 && strings.HasPrefix(literal, "'") && strings.HasSuffix(literal, "'") {
+		value = literal[1 : len(literal)-1]
+	} else if err := json.Unmarshal([]byte(literal), &value); err != nil {
+		return nil, fmt.Errorf("jsonpath: invalid filter value %s", literal)
+	}
+	return &jsonPathFilter{steps: steps, op: op, value: value}, nil
+}
+
+// findJSONPathOperator returns the first comparison operator of the filter
+// outside of the quoted strings, or -1.
+func findJSONPathOperator(s string) (int, string) {
+	var quote byte
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case quote != 0:
+			if c == '\\' {
+				i++
+			} else if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		default:
+			for _, op := range []string{"==", "!=", "<=", ">=", "<", ">"} {
+				if strings.HasPrefix(s[i:], op) {
+					return i, op
+				}
+			}
+		}
+	}
+	return -1, ""
+}
+
+// Execute renders the template against v. v is converted to its json
+// representation first.
+func (jp *JSONPath) Execute(v any) (string, error) {
+	var data any
+	if err := json.Unmarshal(marshalJson(v), &data); err != nil {
+		return "", err
+	}
+	var sb strings.Builder
+	if err := jp.execute(&sb, jp.nodes, data); err != nil {
+		return "", err
+	}
+	return sb.String(), nil
+}
+
+func (jp *JSONPath) execute(sb *strings.Builder, nodes []jsonPathNode, current any) error {
+	for i := 0; i < len(nodes); i++ {
+		node := nodes[i]
+		switch node.kind {
+		case "text":
+			sb.WriteString(node.text)
+		case "expr":
+			values := []string{}
+			for _, r := range evalJSONPath(node.steps, []any{current}) {
+				values = append(values, formatJSONPathValue(r))
+			}
+			sb.WriteString(strings.Join(values, " "))
+		case "range":
+			body := nodes[i+1:]
+			end := findJSONPathEnd(body)
+			if end < 0 {
+				return fmt.Errorf("jsonpath: range without end")
+			}
+			for _, r := range evalJSONPath(node.steps, []any{current}) {
+				if err := jp.execute(sb, body[:end], r); err != nil {
+					return err
+				}
+			}
+			i += end + 1
+		case "end":
+			return fmt.Errorf("jsonpath: end without range")
+		}
+	}
+	return nil
+}
+
+func findJSONPathEnd(nodes []jsonPathNode) int {
+	depth := 0
+	for i, n := range nodes {
+		switch n.kind {
+		case "range":
+			depth++
+		case "end":
+			if depth == 0 {
+				return i
+			}
+			depth--
+		}
+	}
+	return -1
+}
+
+func evalJSONPath(steps []jsonPathStep, current []any) []any {
+	for _, step := range steps {
+		next := []any{}
+		for _, c := range current {
+			next = append(next, evalJSONPathStep(step, c)...)
+		}
+		current = next
+	}
+	return current
+}
+
+func evalJSONPathStep(step jsonPathStep, v any) []any {
+	switch step.kind {
+	case "field":
+		if m, ok := v.(map[string]any); ok {
+			if r, ok := m[step.name]; ok {
+				return []any{r}
+			}
+		}
+	case "wildcard":
+		return jsonPathChildren(v)
+	case "recursive":
+		result := []any{v}
+		for _, c := range jsonPathChildren(v) {
+			result = append(result, evalJSONPathStep(step, c)...)
+		}
+		return result
+	case "index":
+		if a, ok := v.([]any); ok {
+			i := step.index
+			if i < 0 {
+				i += len(a)
+			}
+			if i >= 0 && i < len(a) {
+				return []any{a[i]}
+			}
+		}
+	case "slice":
+		if a, ok := v.([]any); ok {
+			start, end := 0, len(a)
+			if step.start != nil {
+				start = clampJSONPathIndex(*step.start, len(a))
+			}
+			if step.end != nil {
+				end = clampJSONPathIndex(*step.end, len(a))
+			}
+			if start < end {
+				return a[start:end]
+			}
+		}
+	case "filter":
+		result := []any{}
+		for _, c := range jsonPathChildren(v) {
+			if step.filter.match(c) {
+				result = append(result, c)
+			}
+		}
+		return result
+	}
+	return nil
+}
+
+func clampJSONPathIndex(i int, length int) int {
+	if i < 0 {
+		i += length
+	}
+	if i < 0 {
+		return 0
+	}
+	if i > length {
+		return length
+	}
+	return i
+}
+
+func jsonPathChildren(v any) []any {
+	switch t := v.(type) {
+	case []any:
+		return t
+	case map[string]any:
+		keys := []string{}
+		for k := range t {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		children := []any{}
+		for _, k := range keys {
+			children = append(children, t[k])
+		}
+		return children
+	}
+	return nil
+}
+
+func (f *jsonPathFilter) match(v any) bool {
+	results := evalJSONPath(f.steps, []any{v})
+	if f.op == "" {
+		return len(results) > 0
+	}
+	for _, r := range results {
+		if compareJSONPathValue(r, f.op, f.value) {
+			return true
+		}
+	}
+	return false
+}
+
+func compareJSONPathValue(a any, op string, b any) bool {
+	af, aIsNum := a.(float64)
+	bf, bIsNum := b.(float64)
+	if aIsNum && bIsNum {
+		switch op {
+		case "==":
+			return af == bf
+		case "!=":
+			return af != bf
+		case "<":
+			return af < bf
+		case "<=":
+			return af <= bf
+		case ">":
+			return af > bf
+		case ">=":
+			return af >= bf
+		}
+	}
+	as, bs := fmt.Sprint(a), fmt.Sprint(b)
+	switch op {
+	case "==":
+		return as == bs
+	case "!=":
+		return as != bs
+	case "<":
+		return as < bs
+	case "<=":
+		return as <= bs
+	case ">":
+		return as > bs
+	case ">=":
+		return as >= bs
+	}
+	return false
+}
+
+func formatJSONPathValue(v any) string {
+	switch t := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return t
+	case float64:
+		return strconv.FormatFloat(t, 'f', -1, 64)
+	case bool:
+		return strconv.FormatBool(t)
+	default:
+		return strings.TrimSpace(string(marshalJson(t)))
+	}
+}
@@ -0,0 +1,113 @@
+package module
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+const jsonPathTestData = `{
+  "kind": "List",
+  "items": [
+    {"name": "web01", "cpu": 2, "on": true, "tags": {"env": "prod"}, "a.b": "dotted"},
+    {"name": "web02", "cpu": 4, "on": false, "tags": {"env": "dev"}},
+    {"name": "a==b", "cpu": 8, "on": true, "tags": {"env": "it's"}},
+    {"name": "db01", "cpu": 16}
+  ]
+}`
+
+func TestJSONPathExecute(t *testing.T) {
+	var data any
+	if err := json.Unmarshal([]byte(jsonPathTestData), &data); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		template string
+		want     string
+	}{
+		// fields and text
+		{"{.kind}", "List"},
+		{"kind={.kind}", "kind=List"},
+		{"{$.kind}", "List"},
+		{"{.missing}", ""},
+		{`{"a\tb"}`, "a\tb"},
+		// index and slices
+		{"{.items[0].name}", "web01"},
+		{"{.items[-1].name}", "db01"},
+		{"{.items[9].name}", ""},
+		{"{.items[1:3].name}", "web02 a==b"},
+		{"{.items[:2].name}", "web01 web02"},
+		{"{.items[2:].name}", "a==b db01"},
+		{"{.items[-2:].name}", "a==b db01"},
+		{"{.items[3:1].name}", ""},
+		// wildcards and recursive descent
+		{"{.items[*].cpu}", "2 4 8 16"},
+		{"{.items[0].tags.*}", "prod"},
+		{"{..env}", "prod dev it's"},
+		// quoted keys
+		{"{.items[0]['a.b']}", "dotted"},
+		{`{.items[0]["name"]}`, "web01"},
+		// filters
+		{`{.items[?(@.name=="web02")].cpu}`, "4"},
+		{`{.items[?(@.name!="a==b")].name}`, "web01 web02 db01"},
+		{`{.items[?(@.name=="a==b")].cpu}`, "8"},
+		{"{.items[?(@.name=='web01')].cpu}", "2"},
+		{`{.items[?(@.tags.env=="it's")].name}`, "a==b"},
+		{"{.items[?(@.cpu>4)].name}", "a==b db01"},
+		{"{.items[?(@.cpu>=4)].name}", "web02 a==b db01"},
+		{"{.items[?(@.cpu<4)].name}", "web01"},
+		{"{.items[?(@.cpu<=4)].name}", "web01 web02"},
+		{"{.items[?(@.on==true)].name}", "web01 a==b"},
+		{"{.items[?(@.on)].name}", "web01 web02 a==b"},
+		{`{.items[?(@.name>"b")].name}`, "web01 web02 db01"},
+		// range
+		{"{range .items[*]}{.name}:{.cpu};{end}", "web01:2;web02:4;a==b:8;db01:16;"},
+		{"{range .items[?(@.on)]}[{.name}]{end}", "[web01][web02][a==b]"},
+		{`{range .items[0:2]}{.name}{"\n"}{end}`, "web01\nweb02\n"},
+		// objects are printed as json
+		{"{.items[0].tags}", `{
+  "env": "prod"
+}`},
+	}
+	for _, tt := range tests {
+		jp, err := ParseJSONPath(tt.template)
+		if err != nil {
+			t.Errorf("ParseJSONPath(%q): %v", tt.template, err)
+			continue
+		}
+		got, err := jp.Execute(data)
+		if err != nil {
+			t.Errorf("Execute(%q): %v", tt.template, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("Execute(%q) = %q, want %q", tt.template, got, tt.want)
+		}
+	}
+}
+
+func TestJSONPathErrors(t *testing.T) {
+	tests := []string{
+		"{.items[0}",
+		"{.items",
+		"{.items[x]}",
+		"{.items[1:x]}",
+		"{.items[?(@.name==web)]}",
+		`{"unterminated}`,
+	}
+	for _, template := range tests {
+		if _, err := ParseJSONPath(template); err == nil {
+			t.Errorf("ParseJSONPath(%q) succeeded, want an error", template)
+		}
+	}
+
+	for _, template := range []string{"{range .items[*]}{.name}", "{.kind}{end}"} {
+		jp, err := ParseJSONPath(template)
+		if err != nil {
+			t.Errorf("ParseJSONPath(%q): %v", template, err)
+			continue
+		}
+		if _, err := jp.Execute(map[string]any{"items": []any{}}); err == nil {
+			t.Errorf("Execute(%q) succeeded, want an error", template)
+		}
+	}
+}
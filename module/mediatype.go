@@ -0,0 +1,185 @@
+package module
+
+import (
+	"bytes"
+	"encoding/xml"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// apiMediaTypes are the request media types of the apis in apilist, keyed by
+// "METHOD path". They are used when the body alone does not tell, e.g. the
+// admin params which are in the vcloud namespace.
+var apiMediaTypes = map[string]string{
+	"POST /api/admin/orgs":                              "application/vnd.vmware.admin.organization+xml",
+	"PUT /api/admin/org/{id}":                           "application/vnd.vmware.admin.organization+xml",
+	"POST /api/admin/org/{id}/vdcsparams":               "application/vnd.vmware.admin.createVdcParams+xml",
+	"PUT /api/admin/vdc/{id}":                           "application/vnd.vmware.admin.vdc+xml",
+	"POST /api/admin/vdc/{id}/vdcStorageProfiles":       "application/vnd.vmware.admin.updateVdcStorageProfiles+xml",
+	"POST /api/admin/org/{id}/users":                    "application/vnd.vmware.admin.user+xml",
+	"PUT /api/admin/user/{id}":                          "application/vnd.vmware.admin.user+xml",
+	"POST /api/admin/org/{id}/catalogs":                 "application/vnd.vmware.admin.catalog+xml",
+	"PUT /api/vApp/{id}/owner":                          "application/vnd.vmware.vcloud.owner+xml",
+	"PUT /api/vApp/{id}/leaseSettingsSection":           "application/vnd.vmware.vcloud.leaseSettingsSection+xml",
+	"PUT /api/vAppTemplate/{id}/leaseSettingsSection":   "application/vnd.vmware.vcloud.leaseSettingsSection+xml",
+	"PUT /api/vApp/{id}/networkConfigSection":           "application/vnd.vmware.vcloud.networkConfigSection+xml",
+	"PUT /api/vApp/{id}/networkConnectionSection":       "application/vnd.vmware.vcloud.networkConnectionSection+xml",
+	"PUT /api/vApp/{id}/guestCustomizationSection":      "application/vnd.vmware.vcloud.guestCustomizationSection+xml",
+	"PUT /api/vApp/{id}/productSections":                "application/vnd.vmware.vcloud.productSections+xml",
+	"PUT /api/vApp/{id}/startupSection":                 "application/vnd.vmware.vcloud.startupSection+xml",
+	"PUT /api/vApp/{id}/virtualHardwareSection":         "application/vnd.vmware.vcloud.virtualHardwareSection+xml",
+	"POST /api/vApp/{id}/action/deploy":                 "application/vnd.vmware.vcloud.deployVAppParams+xml",
+	"POST /api/vApp/{id}/action/undeploy":               "application/vnd.vmware.vcloud.undeployVAppParams+xml",
+	"POST /api/vApp/{id}/action/recomposeVApp":          "application/vnd.vmware.vcloud.recomposeVAppParams+xml",
+	"POST /api/vApp/{id}/action/reconfigureVm":          "application/vnd.vmware.vcloud.vm+xml",
+	"POST /api/vApp/{id}/action/controlAccess":          "application/vnd.vmware.vcloud.controlAccess+xml",
+	"POST /api/vApp/{id}/action/createSnapshot":         "application/vnd.vmware.vcloud.createSnapshotParams+xml",
+	"POST /api/vApp/{id}/action/relocate":               "application/vnd.vmware.vcloud.relocateVmParams+xml",
+	"POST /api/vApp/{id}/metadata":                      "application/vnd.vmware.vcloud.metadata+xml",
+	"POST /api/vdc/{id}/action/composeVApp":             "application/vnd.vmware.vcloud.composeVAppParams+xml",
+	"POST /api/vdc/{id}/action/instantiateVAppTemplate": "application/vnd.vmware.vcloud.instantiateVAppTemplateParams+xml",
+	"POST /api/vdc/{id}/action/instantiateOvf":          "application/vnd.vmware.vcloud.instantiateOvfParams+xml",
+	"POST /api/vdc/{id}/action/cloneVApp":               "application/vnd.vmware.vcloud.cloneVAppParams+xml",
+	"POST /api/vdc/{id}/action/cloneVAppTemplate":       "application/vnd.vmware.vcloud.cloneVAppTemplateParams+xml",
+	"POST /api/vdc/{id}/action/cloneMedia":              "application/vnd.vmware.vcloud.cloneMediaParams+xml",
+	"POST /api/vdc/{id}/action/captureVApp":             "application/vnd.vmware.vcloud.captureVAppParams+xml",
+	"POST /api/catalog/{id}/action/upload":              "application/vnd.vmware.vcloud.catalogItem+xml",
+	"POST /api/catalog/{id}/action/captureVApp":         "application/vnd.vmware.vcloud.captureVAppParams+xml",
+	"PUT /api/media/{id}":                               "application/vnd.vmware.vcloud.media+xml",
+	// metadata entries, with or without the domain (e.g. SYSTEM). The ones of
+	// the vdcs and the catalogs are written by the admin api.
+	"PUT /api/vApp/{id}/metadata/{key}":                   "application/vnd.vmware.vcloud.metadata.value+xml",
+	"PUT /api/vApp/{id}/metadata/{domain}/{key}":          "application/vnd.vmware.vcloud.metadata.value+xml",
+	"PUT /api/vAppTemplate/{id}/metadata/{key}":           "application/vnd.vmware.vcloud.metadata.value+xml",
+	"PUT /api/vAppTemplate/{id}/metadata/{domain}/{key}":  "application/vnd.vmware.vcloud.metadata.value+xml",
+	"PUT /api/admin/vdc/{id}/metadata/{key}":              "application/vnd.vmware.vcloud.metadata.value+xml",
+	"PUT /api/admin/vdc/{id}/metadata/{domain}/{key}":     "application/vnd.vmware.vcloud.metadata.value+xml",
+	"PUT /api/admin/catalog/{id}/metadata/{key}":          "application/vnd.vmware.vcloud.metadata.value+xml",
+	"PUT /api/admin/catalog/{id}/metadata/{domain}/{key}": "application/vnd.vmware.vcloud.metadata.value+xml",
+	"PUT /api/catalogItem/{id}/metadata/{key}":            "application/vnd.vmware.vcloud.metadata.value+xml",
+	"PUT /api/catalogItem/{id}/metadata/{domain}/{key}":   "application/vnd.vmware.vcloud.metadata.value+xml",
+	"PUT /api/media/{id}/metadata/{key}":                  "application/vnd.vmware.vcloud.metadata.value+xml",
+	"PUT /api/media/{id}/metadata/{domain}/{key}":         "application/vnd.vmware.vcloud.metadata.value+xml",
+}
+
+// apiMediaTypeKeys are the keys of apiMediaTypes, sorted: the lookup does not
+// depend on the order of the map.
+var apiMediaTypeKeys = func() []string {
+	keys := []string{}
+	for key := range apiMediaTypes {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}()
+
+// the namespaces of the request bodies and the prefix of their media types.
+// The extension namespace is not: its media types are not the element names
+// (e.g. VMWExternalNetwork is admin.vmwexternalnet).
+var xmlNamespaceMediaTypes = map[string]string{
+	"":                                       "vcloud",
+	"http://www.vmware.com/vcloud/v1.5":      "vcloud",
+	"http://schemas.dmtf.org/ovf/envelope/1": "vcloud",
+}
+
+// the root elements whose media type is not the element name
+var xmlRootMediaTypes = map[string]string{
+	"AdminOrg":            "admin.organization",
+	"AdminVdc":            "admin.vdc",
+	"AdminCatalog":        "admin.catalog",
+	"CreateVdcParams":     "admin.createVdcParams",
+	"User":                "admin.user",
+	"Group":               "admin.group",
+	"Role":                "admin.role",
+	"Item":                "vcloud.rasdItem",
+	"RasdItemsList":       "vcloud.rasdItemsList",
+	"ControlAccessParams": "vcloud.controlAccess",
+	"MetadataValue":       "vcloud.metadata.value",
+}
+
+// inferContentType returns the media type of the request body: the one of
+// the api in apiMediaTypes, else json for /cloudapi and json bodies, else the
+// one of the root element of a xml body. It returns "" when it can not tell.
+func inferContentType(method string, path string, body []byte) string {
+	if len(bytes.TrimSpace(body)) == 0 {
+		return ""
+	}
+	if mediaType := apiMediaType(method, path); mediaType != "" {
+		return mediaType
+	}
+	trimmed := bytes.TrimSpace(body)
+	if strings.HasPrefix(path, "/cloudapi") || trimmed[0] == '{' || trimmed[0] == '[' {
+		return "application/json"
+	}
+	if trimmed[0] == '<' {
+		return xmlMediaType(trimmed)
+	}
+	return ""
+}
+
+// apiMediaType looks up the path in apiMediaTypes, {..} matching any segment.
+func apiMediaType(method string, path string) string {
+	path, _, _ = strings.Cut(path, "?")
+	for _, key := range apiMediaTypeKeys {
+		m, template, _ := strings.Cut(key, " ")
+		if m == method && matchApiTemplate(template, path) {
+			return apiMediaTypes[key]
+		}
+	}
+	return ""
+}
+
+func matchApiTemplate(template string, path string) bool {
+	templateSegments := strings.Split(strings.TrimSuffix(template, "/"), "/")
+	pathSegments := strings.Split(strings.TrimSuffix(path, "/"), "/")
+	if len(templateSegments) != len(pathSegments) {
+		return false
+	}
+	for i, segment := range templateSegments {
+		if strings.HasPrefix(segment, "{") && strings.HasSuffix(segment, "}") {
+			if pathSegments[i] == "" {
+				return false
+			}
+			continue
+		}
+		if segment != pathSegments[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// xmlMediaType derives the media type from the root element, e.g.
+// <LeaseSettingsSection xmlns="http://www.vmware.com/vcloud/v1.5"> is
+// application/vnd.vmware.vcloud.leaseSettingsSection+xml.
+func xmlMediaType(body []byte) string {
+	decoder := xml.NewDecoder(bytes.NewReader(body))
+	for {
+		token, err := decoder.Token()
+		if err != nil {
+			return ""
+		}
+		start, ok := token.(xml.StartElement)
+		if !ok {
+			continue
+		}
+		if mediaType, ok := xmlRootMediaTypes[start.Name.Local]; ok {
+			return "application/vnd.vmware." + mediaType + "+xml"
+		}
+		prefix, ok := xmlNamespaceMediaTypes[start.Name.Space]
+		if !ok {
+			return ""
+		}
+		return "application/vnd.vmware." + prefix + "." + lowerFirst(start.Name.Local) + "+xml"
+	}
+}
+
+func lowerFirst(s string) string {
+	if s == "" {
+		return s
+	}
+	r := []rune(s)
+	r[0] = unicode.ToLower(r[0])
+	return string(r)
+}
@@ -0,0 +1,75 @@
+package module
+
+import "testing"
+
+func TestInferContentType(t *testing.T) {
+	tests := []struct {
+		method string
+		path   string
+		body   string
+		want   string
+	}{
+		// by the api
+		{"PUT", "/api/vApp/vapp-1/leaseSettingsSection", `<LeaseSettingsSection/>`, "application/vnd.vmware.vcloud.leaseSettingsSection+xml"},
+		{"PUT", "/api/vApp/vapp-1/leaseSettingsSection/", `<x/>`, "application/vnd.vmware.vcloud.leaseSettingsSection+xml"},
+		{"POST", "/api/admin/org/1/vdcsparams?x=1", `<CreateVdcParams/>`, "application/vnd.vmware.admin.createVdcParams+xml"},
+		{"POST", "/api/vApp/vapp-1/action/deploy", `<DeployVAppParams xmlns="http://www.vmware.com/vcloud/v1.5"/>`, "application/vnd.vmware.vcloud.deployVAppParams+xml"},
+		{"PUT", "/api/vApp/vapp-1/metadata/owner", `<MetadataValue/>`, "application/vnd.vmware.vcloud.metadata.value+xml"},
+		{"PUT", "/api/admin/vdc/1/metadata/SYSTEM/owner", `<MetadataValue/>`, "application/vnd.vmware.vcloud.metadata.value+xml"},
+		// json
+		{"POST", "/cloudapi/1.0.0/edgeGateways", `<x/>`, "application/json"},
+		{"PUT", "/api/whatever", ` {"name": "x"}`, "application/json"},
+		{"PUT", "/api/whatever", `[1]`, "application/json"},
+		// by the root element
+		{"PUT", "/api/vApp/vm-1/guestCustomizationSection", `<GuestCustomizationSection xmlns="http://www.vmware.com/vcloud/v1.5"/>`, "application/vnd.vmware.vcloud.guestCustomizationSection+xml"},
+		{"POST", "/api/x", `<?xml version="1.0"?><AdminOrg xmlns="http://www.vmware.com/vcloud/v1.5"/>`, "application/vnd.vmware.admin.organization+xml"},
+		{"PUT", "/api/vApp/vm-1/ovf/section", `<ovf:ProductSection xmlns:ovf="http://schemas.dmtf.org/ovf/envelope/1"/>`, "application/vnd.vmware.vcloud.productSection+xml"},
+		{"PUT", "/api/entity/1/metadata/key", `<MetadataValue xmlns="http://www.vmware.com/vcloud/v1.5"/>`, "application/vnd.vmware.vcloud.metadata.value+xml"},
+		// unknown: no guess
+		{"POST", "/api/admin/extension/externalnets", `<vmext:VMWExternalNetwork xmlns:vmext="http://www.vmware.com/vcloud/extension/v1.5"/>`, ""},
+		{"POST", "/api/x", `<Foo xmlns="urn:other"/>`, ""},
+		{"POST", "/api/x", `name=x`, ""},
+		{"POST", "/api/x", `<broken`, ""},
+		{"POST", "/api/vApp/vapp-1/action/deploy", "  ", ""},
+	}
+	for _, tt := range tests {
+		if got := inferContentType(tt.method, tt.path, []byte(tt.body)); got != tt.want {
+			t.Errorf("inferContentType(%s %s, %q) = %q, want %q", tt.method, tt.path, tt.body, got, tt.want)
+		}
+	}
+}
+
+func TestMatchApiTemplate(t *testing.T) {
+	tests := []struct {
+		template string
+		path     string
+		want     bool
+	}{
+		{"/api/vApp/{id}", "/api/vApp/vapp-1", true},
+		{"/api/vApp/{id}", "/api/vApp/vapp-1/", true},
+		{"/api/vApp/{id}", "/api/vApp/", false},
+		{"/api/vApp/{id}", "/api/vApp/vapp-1/owner", false},
+		{"/api/vApp/{id}/owner", "/api/vApp/vapp-1/owner", true},
+		{"/api/vApp/{id}/owner", "/api/vdc/1/owner", false},
+	}
+	for _, tt := range tests {
+		if got := matchApiTemplate(tt.template, tt.path); got != tt.want {
+			t.Errorf("matchApiTemplate(%q, %q) = %v, want %v", tt.template, tt.path, got, tt.want)
+		}
+	}
+}
+
+func TestApiMediaTypesInApiList(t *testing.T) {
+	apis := map[string]bool{}
+	for _, v := range apilist {
+		apis[v[0]+" "+v[1]] = true
+	}
+	for _, key := range apiMediaTypeKeys {
+		if !apis[key] {
+			t.Errorf("%s of apiMediaTypes is not in apilist", key)
+		}
+	}
+	if len(apiMediaTypeKeys) != len(apiMediaTypes) {
+		t.Errorf("%d keys, want %d", len(apiMediaTypeKeys), len(apiMediaTypes))
+	}
+}
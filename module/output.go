@@ -0,0 +1,304 @@
+package module
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"os"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"text/template"
+
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v2"
+)
+
+var outputFormat string
+
+var outputFormats = []string{"table", "wide", "json", "yaml", "csv", "go-template=", "jsonpath="}
+
+// ResourceList wraps list results so that structured output always has
+// the same shape: {"items": [...]}. go-template sees it as .Items (go field
+// names of the typed structs), jsonpath as .items (json field names).
+type ResourceList struct {
+	Items any `json:"items"`
+}
+
+func addOutputFlag(cmd *cobra.Command) {
+	cmd.PersistentFlags().StringVarP(&outputFormat, "output", "o", "", "output format ("+strings.Join(outputFormats, " | ")+")\n"+
+		"  e.g. -o go-template='{{range .Items}}{{.Id}}{{\"\\n\"}}{{end}}'\n"+
+		"       -o jsonpath='{.items[*].name}'")
+	cmd.RegisterFlagCompletionFunc("output", func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		return outputFormats, cobra.ShellCompDirectiveNoFileComp | cobra.ShellCompDirectiveNoSpace
+	})
+}
+
+// isStructuredOutput reports whether the selected output format is rendered
+// from the underlying structs instead of a hand-made table.
+func isStructuredOutput() bool {
+	return outputFormat != "" && outputFormat != "table"
+}
+
+// PrintResult renders a list result in the selected output format.
+// header and data are used for the default table and csv, items (a slice of
+// structs) for the other formats. When items is nil, the rows are used instead.
+func PrintResult(header []string, data [][]string, items any) {
+	rows := items == nil
+	if rows {
+		items = rowsToMaps(header, data)
+	} else if v := reflect.ValueOf(items); v.Kind() == reflect.Slice && v.IsNil() {
+		items = reflect.MakeSlice(v.Type(), 0, 0).Interface()
+	}
+	format, arg := splitOutputFormat()
+	switch format {
+	case "", "table":
+		PrityPrint(header, data)
+	case "wide":
+		if rows {
+			// the maps of the rows lost the order of the columns
+			PrityPrint(header, data)
+			return
+		}
+		wideHeader, wideData := flattenItems(items)
+		PrityPrint(wideHeader, wideData)
+	case "csv":
+		printCsv(header, data)
+	case "json":
+		printJson(ResourceList{Items: items})
+	case "yaml":
+		printYaml(ResourceList{Items: items})
+	case "go-template":
+		printGoTemplate(arg, ResourceList{Items: items})
+	case "jsonpath":
+		printJSONPath(arg, ResourceList{Items: items})
+	default:
+		Fatal(fmt.Sprintf("unknown output format \"%s\"", outputFormat))
+	}
+}
+
+// PrintObject renders a single object in the selected structured output format.
+func PrintObject(obj any) {
+	format, arg := splitOutputFormat()
+	switch format {
+	case "", "table":
+		// one "Field: value" line per field
+		header, data := flattenItems([]any{obj})
+		for i, h := range header {
+			fmt.Printf("%s: %s\n", h, data[0][i])
+		}
+	case "wide", "csv":
+		header, data := flattenItems([]any{obj})
+		if format == "csv" {
+			printCsv(header, data)
+		} else {
+			PrityPrint(header, data)
+		}
+	case "json":
+		printJson(obj)
+	case "yaml":
+		printYaml(obj)
+	case "go-template":
+		printGoTemplate(arg, obj)
+	case "jsonpath":
+		printJSONPath(arg, obj)
+	default:
+		Fatal(fmt.Sprintf("unknown output format \"%s\"", outputFormat))
+	}
+}
+
+// splitOutputFormat splits "jsonpath={.items}" into the format name and its
+// template argument.
+func splitOutputFormat() (string, string) {
+	format, arg, _ := strings.Cut(outputFormat, "=")
+	return format, arg
+}
+
+func rowsToMaps(header []string, data [][]string) []map[string]string {
+	rows := []map[string]string{}
+	for _, v := range data {
+		row := map[string]string{}
+		for i, h := range header {
+			row[h] = v[i]
+		}
+		rows = append(rows, row)
+	}
+	return rows
+}
+
+func marshalJson(v any) []byte {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(v); err != nil {
+		Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func printJson(v any) {
+	os.Stdout.Write(marshalJson(v))
+}
+
+func printYaml(v any) {
+	// go through json so that yaml keys are the same as json keys,
+	// MapSlice keeps the field order of the structs
+	var doc yaml.MapSlice
+	if err := yaml.Unmarshal(marshalJson(v), &doc); err != nil {
+		Fatal(err)
+	}
+	out, err := yaml.Marshal(doc)
+	if err != nil {
+		Fatal(err)
+	}
+	os.Stdout.Write(out)
+}
+
+func printGoTemplate(text string, v any) {
+	tmpl, err := template.New("output").Parse(text)
+	if err != nil {
+		Fatal(err)
+	}
+	if err := tmpl.Execute(os.Stdout, v); err != nil {
+		Fatal(err)
+	}
+}
+
+func printJSONPath(text string, v any) {
+	jp, err := ParseJSONPath(text)
+	if err != nil {
+		Fatal(err)
+	}
+	out, err := jp.Execute(v)
+	if err != nil {
+		Fatal(err)
+	}
+	fmt.Println(out)
+}
+
+func printCsv(header []string, data [][]string) {
+	w := csv.NewWriter(os.Stdout)
+	w.Write(header)
+	for _, v := range data {
+		w.Write(v[:len(header)])
+	}
+	w.Flush()
+	if err := w.Error(); err != nil {
+		Fatal(err)
+	}
+}
+
+// flattenItems converts a slice of structs to a table with one column per
+// scalar field. Nested struct fields are named "Parent.Child".
+func flattenItems(items any) ([]string, [][]string) {
+	v := reflect.ValueOf(items)
+	if v.Kind() != reflect.Slice {
+		Fatal(fmt.Sprintf("cannot render %T as table", items))
+	}
+
+	header := []string{}
+	data := [][]string{}
+	for i := 0; i < v.Len(); i++ {
+		item := reflect.Indirect(v.Index(i))
+		if item.Kind() == reflect.Interface {
+			item = reflect.Indirect(item.Elem())
+		}
+		if item.Kind() == reflect.Map {
+			h, row := flattenMap(item)
+			if i == 0 {
+				header = h
+			}
+			data = append(data, row)
+			continue
+		}
+		if i == 0 {
+			header = flattenHeader(item.Type(), "")
+		}
+		data = append(data, flattenValue(item))
+	}
+	return header, data
+}
+
+// flattenMap flattens a map, whose keys have no declared order: they are sorted.
+func flattenMap(v reflect.Value) ([]string, []string) {
+	header := []string{}
+	for _, k := range v.MapKeys() {
+		header = append(header, k.String())
+	}
+	sort.Strings(header)
+	row := []string{}
+	for _, k := range header {
+		row = append(row, fmt.Sprint(v.MapIndex(reflect.ValueOf(k)).Interface()))
+	}
+	return header, row
+}
+
+func isFlattenedStruct(t reflect.Type) bool {
+	return t.Kind() == reflect.Struct && t != reflect.TypeOf(xml.Name{})
+}
+
+func flattenHeader(t reflect.Type, prefix string) []string {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if !isFlattenedStruct(t) {
+		return []string{strings.TrimSuffix(prefix, ".")}
+	}
+	header := []string{}
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if !f.IsExported() || f.Type == reflect.TypeOf(xml.Name{}) {
+			continue
+		}
+		header = append(header, flattenHeader(f.Type, prefix+f.Name+".")...)
+	}
+	return header
+}
+
+func flattenValue(v reflect.Value) []string {
+	t := v.Type()
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+		if v.IsNil() {
+			return make([]string, len(flattenHeader(t, "")))
+		}
+		v = v.Elem()
+	}
+	if !isFlattenedStruct(t) {
+		return []string{formatScalar(v)}
+	}
+	row := []string{}
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if !f.IsExported() || f.Type == reflect.TypeOf(xml.Name{}) {
+			continue
+		}
+		row = append(row, flattenValue(v.Field(i))...)
+	}
+	return row
+}
+
+func formatScalar(v reflect.Value) string {
+	switch v.Kind() {
+	case reflect.String:
+		return v.String()
+	case reflect.Bool:
+		return strconv.FormatBool(v.Bool())
+	case reflect.Slice:
+		if v.Type().Elem().Kind() == reflect.String {
+			return strings.Join(v.Interface().([]string), ",")
+		}
+		return strconv.Itoa(v.Len())
+	case reflect.Interface:
+		if v.IsNil() {
+			return ""
+		}
+		return fmt.Sprint(v.Interface())
+	default:
+		return fmt.Sprint(v.Interface())
+	}
+}
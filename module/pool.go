@@ -0,0 +1,44 @@
+package module
+
+import (
+	"errors"
+	"sync"
+)
+
+// set by --concurrency
+var concurrency = 8
+
+// parallelMap calls fn for each item, with at most --concurrency calls at a
+// time. The results are in the order of the items. All the errors are
+// returned joined, the results of the failed items are zero values.
+func parallelMap[T any, R any](items []T, fn func(T) (R, error)) ([]R, error) {
+	results := make([]R, len(items))
+	errs := make([]error, len(items))
+
+	workers := concurrency
+	if workers < 1 {
+		workers = 1
+	}
+	if workers > len(items) {
+		workers = len(items)
+	}
+
+	indexes := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indexes {
+				results[i], errs[i] = fn(items[i])
+			}
+		}()
+	}
+	for i := range items {
+		indexes <- i
+	}
+	close(indexes)
+	wg.Wait()
+
+	return results, errors.Join(errs...)
+}
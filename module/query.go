@@ -0,0 +1,254 @@
+package module
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+// set by --filter and --limit
+var (
+	queryFilter string
+	queryLimit  int
+)
+
+// the largest page size accepted by vCD
+const maxQueryPageSize = 128
+
+// Query holds the parameters of the typed query service (/api/query) and
+// the CloudAPI collections, which share the FIQL filter syntax, e.g.
+// "name==web*;status==POWERED_ON".
+type Query struct {
+	Filter   string
+	SortAsc  string
+	SortDesc string
+	PageSize int
+	Page     int
+	// Fields limits the attributes of the records (query service only).
+	Fields []string
+	// Limit stops the paginators after this many results (all when 0).
+	Limit int
+}
+
+// listQuery returns the query given by --filter and --limit.
+func listQuery() Query {
+	return Query{Filter: queryFilter, Limit: queryLimit}
+}
+
+// Values returns the query as url parameters.
+func (q Query) Values() url.Values {
+	values := url.Values{}
+	if q.Filter != "" {
+		values.Set("filter", q.Filter)
+	}
+	if q.SortAsc != "" {
+		values.Set("sortAsc", q.SortAsc)
+	}
+	if q.SortDesc != "" {
+		values.Set("sortDesc", q.SortDesc)
+	}
+	if q.PageSize > 0 {
+		values.Set("pageSize", strconv.Itoa(q.PageSize))
+	}
+	if q.Page > 0 {
+		values.Set("page", strconv.Itoa(q.Page))
+	}
+	if len(q.Fields) > 0 {
+		values.Set("fields", strings.Join(q.Fields, ","))
+	}
+	return values
+}
+
+// fiqlReserved are the characters of the FIQL syntax. vCD decodes the
+// percent-encoded ones in a value after parsing the filter, so a name like
+// "web (prod);a,b" is matched literally.
+const fiqlReserved = "%;,()=!<>~*'\""
+
+// fiqlEscape percent-encodes the FIQL characters of a value. The url encoding
+// of the whole parameter is done by Query.Values.
+func fiqlEscape(value string) string {
+	var b strings.Builder
+	for i := 0; i < len(value); i++ {
+		c := value[i]
+		if strings.IndexByte(fiqlReserved, c) >= 0 {
+			fmt.Fprintf(&b, "%%%02X", c)
+		} else {
+			b.WriteByte(c)
+		}
+	}
+	return b.String()
+}
+
+// fiqlEq returns the filter matching the field with the value as is.
+func fiqlEq(field string, value string) string {
+	return field + "==" + fiqlEscape(value)
+}
+
+// andFilter combines FIQL filters with AND, skipping the empty ones.
+func andFilter(filters ...string) string {
+	parts := []string{}
+	for _, f := range filters {
+		if f != "" {
+			parts = append(parts, f)
+		}
+	}
+	if len(parts) < 2 {
+		return strings.Join(parts, "")
+	}
+	return "(" + strings.Join(parts, ");(") + ")"
+}
+
+// QueryRecords runs the typed query (/api/query?type=...) and decodes a
+// page of the records into result, e.g. *OrgVdcList.
+func QueryRecords(queryType string, q Query, result any) error {
+	_, err := getXml(queryRecordsApi(queryType, q), result)
+	return err
+}
+
+func queryRecordsApi(queryType string, q Query) string {
+	values := q.Values()
+	values.Set("type", queryType)
+	values.Set("format", "records")
+	return "/api/query?" + values.Encode()
+}
+
+func getXml(api string, result any) (*Response, error) {
+	res, err := client.Request("GET", api, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	if err := xml.Unmarshal(res.Body, result); err != nil {
+		return nil, responseError(res, err)
+	}
+	return res, nil
+}
+
+// recordsPage is a page of the query service. The records are all the
+// elements but the links, e.g. AdminVdcRecord.
+type recordsPage[T any] struct {
+	Links   []Link `xml:"Link"`
+	Records []T    `xml:",any"`
+}
+
+type Link struct {
+	Rel  string `xml:"rel,attr"`
+	Href string `xml:"href,attr"`
+}
+
+// QueryAllRecords runs the typed query and follows the nextPage links until
+// the last page, or until q.Limit records.
+func QueryAllRecords[T any](queryType string, q Query) ([]T, error) {
+	if q.PageSize == 0 && q.Limit > 0 {
+		q.PageSize = min(q.Limit, maxQueryPageSize)
+	}
+	records := []T{}
+	api := queryRecordsApi(queryType, q)
+	for api != "" {
+		var page recordsPage[T]
+		res, err := getXml(api, &page)
+		if err != nil {
+			return nil, err
+		}
+		records = append(records, page.Records...)
+		if q.Limit > 0 && len(records) >= q.Limit {
+			return records[:q.Limit], nil
+		}
+		if len(page.Records) == 0 {
+			break
+		}
+		api = nextPageApi(res, page.Links)
+	}
+	return records, nil
+}
+
+// nextPageApi returns the path of the nextPage link of the body, or of the
+// Link header, or "" on the last page.
+func nextPageApi(res *Response, links []Link) string {
+	for _, link := range links {
+		if link.Rel == "nextPage" {
+			return requestUri(link.Href)
+		}
+	}
+	for _, header := range res.Header["Link"] {
+		for _, value := range strings.Split(header, ",") {
+			parts := strings.Split(value, ";")
+			for _, param := range parts[1:] {
+				if strings.TrimSpace(param) == `rel="nextPage"` {
+					return requestUri(strings.Trim(strings.TrimSpace(parts[0]), "<>"))
+				}
+			}
+		}
+	}
+	return ""
+}
+
+// requestUri strips the endpoint from the link, which the client prepends.
+func requestUri(href string) string {
+	u, err := url.Parse(href)
+	if err != nil {
+		return ""
+	}
+	return u.RequestURI()
+}
+
+// QueryCloudApi gets a page of the CloudAPI collection (e.g.
+// /cloudapi/1.0.0/edgeGateways) and decodes it into result, a struct with
+// Values. QueryAllCloudApi gets all the pages.
+func QueryCloudApi(path string, q Query, result any) error {
+	api := path
+	if values := q.Values(); len(values) > 0 {
+		api += "?" + values.Encode()
+	}
+	res, err := client.Request("GET", api, nil, nil)
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(res.Body, result); err != nil {
+		return responseError(res, err)
+	}
+	return nil
+}
+
+// cloudApiPage is a page of a CloudAPI collection.
+type cloudApiPage[T any] struct {
+	Page      int `json:"page"`
+	PageCount int `json:"pageCount"`
+	Values    []T `json:"values"`
+}
+
+// QueryAllCloudApi gets the pages of the CloudAPI collection until the last
+// one (pageCount), or until q.Limit values.
+func QueryAllCloudApi[T any](path string, q Query) ([]T, error) {
+	if q.PageSize == 0 && q.Limit > 0 {
+		q.PageSize = min(q.Limit, maxQueryPageSize)
+	}
+	if q.Page == 0 {
+		q.Page = 1
+	}
+	values := []T{}
+	for {
+		var page cloudApiPage[T]
+		if err := QueryCloudApi(path, q, &page); err != nil {
+			return nil, err
+		}
+		values = append(values, page.Values...)
+		if q.Limit > 0 && len(values) >= q.Limit {
+			return values[:q.Limit], nil
+		}
+		if len(page.Values) == 0 || page.Page >= page.PageCount {
+			return values, nil
+		}
+		q.Page = page.Page + 1
+	}
+}
+
+// addListFlags adds --filter and --limit to the listing commands.
+func addListFlags(cmd *cobra.Command) {
+	cmd.Flags().StringVar(&queryFilter, "filter", "", "FIQL filter evaluated by vCD, e.g. 'name==web*;status==POWERED_ON'")
+	cmd.Flags().IntVar(&queryLimit, "limit", 0, "show at most this many results (all pages when 0)")
+}
@@ -0,0 +1,85 @@
+package module
+
+import (
+	"net/url"
+	"testing"
+)
+
+func TestFiqlEscape(t *testing.T) {
+	tests := []struct {
+		value string
+		want  string
+	}{
+		{"web01", "web01"},
+		{"my vdc", "my vdc"},
+		{"web (prod)", "web %28prod%29"},
+		{"a;b,c", "a%3Bb%2Cc"},
+		{"x==y", "x%3D%3Dy"},
+		{"100%", "100%25"},
+		{"*", "%2A"},
+		{`it's "q"`, "it%27s %22q%22"},
+		{"!<>~", "%21%3C%3E%7E"},
+		{"日本語", "日本語"},
+		{"urn:vcloud:vdc:1", "urn:vcloud:vdc:1"},
+	}
+	for _, tt := range tests {
+		if got := fiqlEscape(tt.value); got != tt.want {
+			t.Errorf("fiqlEscape(%q) = %q, want %q", tt.value, got, tt.want)
+		}
+	}
+}
+
+func TestAndFilter(t *testing.T) {
+	tests := []struct {
+		filters []string
+		want    string
+	}{
+		{nil, ""},
+		{[]string{"", ""}, ""},
+		{[]string{"name==a"}, "name==a"},
+		{[]string{"", "name==a"}, "name==a"},
+		{[]string{"name==a", "status==1,status==2"}, "(name==a);(status==1,status==2)"},
+		{[]string{fiqlEq("name", "a;b"), fiqlEq("orgVdc.name", "v (1)")}, "(name==a%3Bb);(orgVdc.name==v %281%29)"},
+	}
+	for _, tt := range tests {
+		if got := andFilter(tt.filters...); got != tt.want {
+			t.Errorf("andFilter(%q) = %q, want %q", tt.filters, got, tt.want)
+		}
+	}
+}
+
+func TestQueryValues(t *testing.T) {
+	q := Query{
+		Filter:   andFilter(fiqlEq("name", "web (prod);a,b & c"), "status==POWERED_ON"),
+		SortAsc:  "name",
+		PageSize: 10,
+		Page:     2,
+		Fields:   []string{"name", "status"},
+	}
+	encoded := q.Values().Encode()
+	want := "fields=name%2Cstatus&filter=%28name%3D%3Dweb+%2528prod%2529%253Ba%252Cb+%26+c%29%3B%28status%3D%3DPOWERED_ON%29&page=2&pageSize=10&sortAsc=name"
+	if encoded != want {
+		t.Errorf("Values().Encode() = %q, want %q", encoded, want)
+	}
+
+	// the server gets back the filter with the escaped value
+	values, err := url.ParseQuery(encoded)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := values.Get("filter"), "(name==web %28prod%29%3Ba%2Cb & c);(status==POWERED_ON)"; got != want {
+		t.Errorf("filter = %q, want %q", got, want)
+	}
+
+	if encoded := (Query{}).Values().Encode(); encoded != "" {
+		t.Errorf("empty query encoded to %q", encoded)
+	}
+}
+
+func TestQueryRecordsApi(t *testing.T) {
+	got := queryRecordsApi("adminOrgVdc", Query{Filter: fiqlEq("name", "a&b=c")})
+	want := "/api/query?filter=name%3D%3Da%26b%253Dc&format=records&type=adminOrgVdc"
+	if got != want {
+		t.Errorf("queryRecordsApi() = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,244 @@
+package module
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// set by --record and --replay
+var (
+	recordDir string
+	replayDir string
+)
+
+const redacted = "REDACTED"
+
+// the token of the client in replay mode, the recorded tokens are redacted
+const replayToken = "replay"
+
+// Exchange is a recorded request and its response, one file per exchange.
+type Exchange struct {
+	Method         string              `json:"method"`
+	Path           string              `json:"path"`
+	RequestHeader  map[string][]string `json:"requestHeader,omitempty"`
+	RequestBody    string              `json:"requestBody,omitempty"`
+	Status         int                 `json:"status"`
+	ResponseHeader map[string][]string `json:"responseHeader,omitempty"`
+	ResponseBody   string              `json:"responseBody,omitempty"`
+}
+
+// wrapTransport returns the transport recording to --record, or replaying
+// --replay instead of sending the requests.
+func wrapTransport(transport http.RoundTripper) (http.RoundTripper, error) {
+	if replayDir != "" {
+		return newReplayTransport(replayDir)
+	}
+	if recordDir != "" {
+		return newRecordingTransport(transport, recordDir)
+	}
+	return transport, nil
+}
+
+// the headers holding credentials or tokens, redacted when recorded
+var secretHeaders = []string{
+	"Authorization",
+	"Cookie",
+	"Set-Cookie",
+	"X-Vcloud-Authorization",
+	"X-Vmware-Vcloud-Access-Token",
+}
+
+// the body fields holding credentials or tokens: json, xml and form fields
+var secretBodyPatterns = []struct {
+	re          *regexp.Regexp
+	replacement string
+}{
+	{regexp.MustCompile(`("(?i:password|access_token|refresh_token|token|secret)"\s*:\s*)"(?:[^"\\]|\\.)*"`), `${1}"` + redacted + `"`},
+	{regexp.MustCompile(`(<(?:\w+:)?(?:Admin)?Password(?:\s[^>]*)?>)[^<]*`), "${1}" + redacted},
+	{regexp.MustCompile(`((?:^|&)(?i:password|refresh_token|access_token)=)[^&]*`), "${1}" + redacted},
+}
+
+func redactHeader(header http.Header) map[string][]string {
+	result := map[string][]string{}
+	for key, values := range header {
+		result[key] = values
+	}
+	for _, key := range secretHeaders {
+		if _, ok := result[key]; ok {
+			result[key] = []string{redacted}
+		}
+	}
+	return result
+}
+
+func redactBody(body []byte) string {
+	s := string(body)
+	for _, p := range secretBodyPatterns {
+		s = p.re.ReplaceAllString(s, p.replacement)
+	}
+	return s
+}
+
+// recordingTransport sends the requests and writes each exchange, redacted,
+// to the directory.
+type recordingTransport struct {
+	next http.RoundTripper
+	dir  string
+	mu   sync.Mutex
+	seq  int
+}
+
+func newRecordingTransport(next http.RoundTripper, dir string) (*recordingTransport, error) {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, err
+	}
+	// numbered after the exchanges of the previous commands
+	existing, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		return nil, err
+	}
+	return &recordingTransport{next: next, dir: dir, seq: len(existing)}, nil
+}
+
+func (t *recordingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	var reqBody []byte
+	if req.Body != nil {
+		var err error
+		if reqBody, err = io.ReadAll(req.Body); err != nil {
+			return nil, err
+		}
+		req.Body.Close()
+		req.Body = io.NopCloser(bytes.NewReader(reqBody))
+	}
+
+	res, err := t.next.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	resBody, err := io.ReadAll(res.Body)
+	res.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	res.Body = io.NopCloser(bytes.NewReader(resBody))
+
+	exchange := Exchange{
+		Method:         req.Method,
+		Path:           req.URL.RequestURI(),
+		RequestHeader:  redactHeader(req.Header),
+		RequestBody:    redactBody(reqBody),
+		Status:         res.StatusCode,
+		ResponseHeader: redactHeader(res.Header),
+		ResponseBody:   redactBody(resBody),
+	}
+	if err := t.write(exchange); err != nil {
+		Log(fmt.Sprintf("failed to record %s %s: %v", req.Method, exchange.Path, err))
+	}
+	return res, nil
+}
+
+var unsafeFileNameChars = regexp.MustCompile(`[^A-Za-z0-9.]+`)
+
+func (t *recordingTransport) write(exchange Exchange) error {
+	// the bodies are easier to read without <, > and & escaped
+	var data bytes.Buffer
+	encoder := json.NewEncoder(&data)
+	encoder.SetEscapeHTML(false)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(exchange); err != nil {
+		return err
+	}
+	t.mu.Lock()
+	t.seq++
+	seq := t.seq
+	t.mu.Unlock()
+
+	path := strings.SplitN(exchange.Path, "?", 2)[0]
+	name := strings.Trim(unsafeFileNameChars.ReplaceAllString(path, "-"), "-")
+	name = fmt.Sprintf("%04d-%s-%s.json", seq, exchange.Method, Truncate(name, 60))
+	return os.WriteFile(filepath.Join(t.dir, name), data.Bytes(), 0600)
+}
+
+// replayTransport answers the requests with the recorded responses, without
+// network access. The exchanges are used in the recorded order: the first
+// unused one with the same method and path, or the last one when all are
+// used (e.g. polling a task).
+type replayTransport struct {
+	mu        sync.Mutex
+	exchanges []Exchange
+	used      []bool
+}
+
+func newReplayTransport(dir string) (*replayTransport, error) {
+	files, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		return nil, err
+	}
+	if len(files) == 0 {
+		return nil, fmt.Errorf("no recorded exchange in %s", dir)
+	}
+	sort.Strings(files)
+
+	t := &replayTransport{}
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+		var exchange Exchange
+		if err := json.Unmarshal(data, &exchange); err != nil {
+			return nil, fmt.Errorf("%s: %w", file, err)
+		}
+		t.exchanges = append(t.exchanges, exchange)
+	}
+	t.used = make([]bool, len(t.exchanges))
+	return t, nil
+}
+
+func (t *replayTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Body != nil {
+		req.Body.Close()
+	}
+	path := req.URL.RequestURI()
+
+	t.mu.Lock()
+	found := -1
+	for i, exchange := range t.exchanges {
+		if exchange.Method != req.Method || exchange.Path != path {
+			continue
+		}
+		found = i
+		if !t.used[i] {
+			break
+		}
+	}
+	if found >= 0 {
+		t.used[found] = true
+	}
+	t.mu.Unlock()
+
+	if found < 0 {
+		return nil, fmt.Errorf("no recorded response for %s %s", req.Method, path)
+	}
+	exchange := t.exchanges[found]
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", exchange.Status, http.StatusText(exchange.Status)),
+		StatusCode:    exchange.Status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        http.Header(exchange.ResponseHeader),
+		Body:          io.NopCloser(strings.NewReader(exchange.ResponseBody)),
+		ContentLength: int64(len(exchange.ResponseBody)),
+		Request:       req,
+	}, nil
+}
@@ -0,0 +1,317 @@
+package module
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRedactHeader(t *testing.T) {
+	header := http.Header{
+		"Authorization":                {"Bearer secret-token"},
+		"X-Vmware-Vcloud-Access-Token": {"secret-token"},
+		"Set-Cookie":                   {"vcloud-token=secret"},
+		"Accept":                       {"application/*;version=37.0"},
+	}
+	got := redactHeader(header)
+	for _, key := range []string{"Authorization", "X-Vmware-Vcloud-Access-Token", "Set-Cookie"} {
+		if len(got[key]) != 1 || got[key][0] != redacted {
+			t.Errorf("%s = %v, want [%s]", key, got[key], redacted)
+		}
+	}
+	if got["Accept"][0] != "application/*;version=37.0" {
+		t.Errorf("Accept = %v, want it kept", got["Accept"])
+	}
+	if header.Get("Authorization") != "Bearer secret-token" {
+		t.Error("redactHeader modified the request header")
+	}
+}
+
+func TestRedactBody(t *testing.T) {
+	tests := []struct {
+		body string
+		want string
+	}{
+		{`{"name":"admin","password":"p@ss\"word"}`, `{"name":"admin","password":"REDACTED"}`},
+		{`{"access_token": "abc", "refresh_token":"def"}`, `{"access_token": "REDACTED", "refresh_token":"REDACTED"}`},
+		{`<User><Password>secret</Password></User>`, `<User><Password>REDACTED</Password></User>`},
+		{`<vcloud:AdminPassword xsi:nil="false">secret</vcloud:AdminPassword>`, `<vcloud:AdminPassword xsi:nil="false">REDACTED</vcloud:AdminPassword>`},
+		{`<PasswordPolicy><PasswordLength>8</PasswordLength></PasswordPolicy>`, `<PasswordPolicy><PasswordLength>8</PasswordLength></PasswordPolicy>`},
+		{`grant_type=refresh_token&refresh_token=secret`, `grant_type=refresh_token&refresh_token=REDACTED`},
+		{`<Name>password</Name>`, `<Name>password</Name>`},
+	}
+	for _, tt := range tests {
+		if got := redactBody([]byte(tt.body)); got != tt.want {
+			t.Errorf("redactBody(%q) = %q, want %q", tt.body, got, tt.want)
+		}
+	}
+}
+
+func TestRecordingTransport(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Vmware-Vcloud-Access-Token", "secret-token")
+		fmt.Fprint(w, `<User><Password>secret</Password></User>`)
+	}))
+	defer srv.Close()
+
+	dir := t.TempDir()
+	// numbered after the exchanges already in the directory
+	if err := os.WriteFile(filepath.Join(dir, "0001-GET-api.json"), []byte("{}"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	transport, err := newRecordingTransport(http.DefaultTransport, dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req, _ := http.NewRequest("POST", srv.URL+"/api/admin/org/1/users?x=1", strings.NewReader(`<User><Password>p</Password></User>`))
+	req.Header.Set("Authorization", "Basic c2VjcmV0")
+	res, err := transport.RoundTrip(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := io.ReadAll(res.Body)
+	if string(body) != `<User><Password>secret</Password></User>` {
+		t.Errorf("response body = %q, want it unchanged", body)
+	}
+
+	data, err := os.ReadFile(filepath.Join(dir, "0002-POST-api-admin-org-1-users.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var exchange Exchange
+	if err := json.Unmarshal(data, &exchange); err != nil {
+		t.Fatal(err)
+	}
+	if exchange.Path != "/api/admin/org/1/users?x=1" || exchange.Status != 200 {
+		t.Errorf("recorded %s %d", exchange.Path, exchange.Status)
+	}
+	if exchange.RequestHeader["Authorization"][0] != redacted || exchange.ResponseHeader["X-Vmware-Vcloud-Access-Token"][0] != redacted {
+		t.Errorf("headers not redacted: %v %v", exchange.RequestHeader, exchange.ResponseHeader)
+	}
+	if strings.Contains(string(data), "secret") || strings.Contains(exchange.RequestBody, "<Password>p<") {
+		t.Errorf("secrets recorded:\n%s", data)
+	}
+}
+
+func writeExchanges(t *testing.T, exchanges ...Exchange) string {
+	dir := t.TempDir()
+	for i, exchange := range exchanges {
+		data, err := json.Marshal(exchange)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(dir, fmt.Sprintf("%04d.json", i+1)), data, 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func TestReplayTransport(t *testing.T) {
+	dir := writeExchanges(t,
+		Exchange{Method: "GET", Path: "/api/task/1", Status: 200, ResponseBody: "running"},
+		Exchange{Method: "POST", Path: "/api/task/1", Status: 202, ResponseBody: "posted"},
+		Exchange{Method: "GET", Path: "/api/task/1", Status: 200, ResponseBody: "success"},
+		Exchange{Method: "GET", Path: "/api/task/1?x=1", Status: 404, ResponseBody: "query"},
+	)
+	transport, err := newReplayTransport(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		method string
+		path   string
+		status int
+		body   string
+	}{
+		// in the recorded order, then the last one again (polling)
+		{"GET", "/api/task/1", 200, "running"},
+		{"GET", "/api/task/1", 200, "success"},
+		{"GET", "/api/task/1", 200, "success"},
+		// the method and the query string are matched
+		{"POST", "/api/task/1", 202, "posted"},
+		{"GET", "/api/task/1?x=1", 404, "query"},
+	}
+	for _, tt := range tests {
+		req, _ := http.NewRequest(tt.method, "https://vcd.example.com"+tt.path, nil)
+		res, err := transport.RoundTrip(req)
+		if err != nil {
+			t.Fatalf("%s %s: %v", tt.method, tt.path, err)
+		}
+		body, _ := io.ReadAll(res.Body)
+		if res.StatusCode != tt.status || string(body) != tt.body {
+			t.Errorf("%s %s = %d %q, want %d %q", tt.method, tt.path, res.StatusCode, body, tt.status, tt.body)
+		}
+	}
+
+	req, _ := http.NewRequest("DELETE", "https://vcd.example.com/api/task/1", nil)
+	if _, err := transport.RoundTrip(req); err == nil || !strings.Contains(err.Error(), "no recorded response") {
+		t.Errorf("unrecorded request: err = %v", err)
+	}
+	if _, err := newReplayTransport(t.TempDir()); err == nil {
+		t.Error("empty replay directory: want an error")
+	}
+}
+
+// captureStdout returns what f prints to stdout.
+func captureStdout(t *testing.T, f func()) string {
+	t.Helper()
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	stdout := os.Stdout
+	os.Stdout = w
+	done := make(chan []byte)
+	go func() {
+		data, _ := io.ReadAll(r)
+		done <- data
+	}()
+	defer func() {
+		os.Stdout = stdout
+	}()
+	f()
+	w.Close()
+	return string(<-done)
+}
+
+// connectReplay makes the client answered by the exchanges of the replay
+// directory, for the functions which return their errors.
+func connectReplay(t *testing.T, replay string) {
+	t.Helper()
+	replayDir = filepath.Join("testdata", "replay", replay)
+	t.Cleanup(func() {
+		replayDir, client = "", VcdClient{}
+	})
+	site := Site{Name: "lab", Endpoint: "https://vcd.example.com", User: "admin@System", OrgName: "System", ApiVersion: "37.0"}
+	if err := Connect(site); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// TestVcdctlProcess runs vcdctl with the arguments of VCDCTL_TEST_ARGS, in
+// the subprocess started by replayVcdctl: Fatal exits the process.
+func TestVcdctlProcess(t *testing.T) {
+	argsJson := os.Getenv("VCDCTL_TEST_ARGS")
+	if argsJson == "" {
+		t.Skip("run by replayVcdctl")
+	}
+	var args []string
+	if err := json.Unmarshal([]byte(argsJson), &args); err != nil {
+		t.Fatal(err)
+	}
+	taskPollInterval = time.Millisecond
+	cmd := GetCmdRoot()
+	cmd.SetArgs(args)
+	if err := cmd.Execute(); err != nil {
+		os.Exit(ExitError)
+	}
+	os.Exit(0)
+}
+
+// replayVcdctl runs vcdctl in a subprocess with the site of a config written
+// to a temporary file, answered by the exchanges of the replay directory. It
+// returns the stdout, the stderr and the exit code.
+func replayVcdctl(t *testing.T, replay string, args ...string) (string, string, int) {
+	t.Helper()
+	configPath := filepath.Join(t.TempDir(), "vcdctl.json")
+	siteConfig := `{
+  "current-site": "lab",
+  "sites": [{"name": "lab", "endpoint": "https://vcd.example.com", "user": "admin@System", "password": "c2VjcmV0", "orgname": "System", "apiversion": "37.0"}]
+}`
+	if err := os.WriteFile(configPath, []byte(siteConfig), 0600); err != nil {
+		t.Fatal(err)
+	}
+	args = append([]string{"--config", configPath, "--replay", filepath.Join("testdata", "replay", replay)}, args...)
+	argsJson, err := json.Marshal(args)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cmd := exec.Command(os.Args[0], "-test.run=^TestVcdctlProcess$")
+	cmd.Env = append(os.Environ(), "VCDCTL_TEST_ARGS="+string(argsJson))
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	err = cmd.Run()
+	var exitErr *exec.ExitError
+	if err != nil && !errors.As(err, &exitErr) {
+		t.Fatal(err)
+	}
+	return stdout.String(), stderr.String(), cmd.ProcessState.ExitCode()
+}
+
+// runReplay runs vcdctl like replayVcdctl and fails the test unless it
+// succeeds. It returns the stdout.
+func runReplay(t *testing.T, replay string, args ...string) string {
+	t.Helper()
+	stdout, stderr, code := replayVcdctl(t, replay, args...)
+	if code != 0 {
+		t.Fatalf("vcdctl %s exited with %d:\n%s", strings.Join(args, " "), code, stderr)
+	}
+	return stdout
+}
+
+func TestReplayGetOrg(t *testing.T) {
+	got := runReplay(t, "get-org", "get", "org", "-o", "jsonpath={range .items[*]}{.name} {.id}{\"\\n\"}{end}")
+	want := "tenant-a 8a1b2c3d-0000-4000-8000-000000000001\ntenant-b 8a1b2c3d-0000-4000-8000-000000000002\n\n"
+	if got != want {
+		t.Errorf("get org = %q, want %q", got, want)
+	}
+}
+
+func TestReplayGetOrgFilter(t *testing.T) {
+	got := runReplay(t, "get-org", "get", "org", "--filter", "name==tenant-*", "--limit", "1", "-o", "jsonpath={.items[*].name}")
+	if got != "tenant-a\n" {
+		t.Errorf("get org --filter --limit = %q", got)
+	}
+}
+
+func TestReplayCreateVdcNetworkWait(t *testing.T) {
+	_, stderr, code := replayVcdctl(t, "create-vdc-network", "create", "vdc-network", "net-a", "--orgvdc", "vdc-a", "--type", "ISOLATED", "--cidr", "192.168.10.1/24", "--wait")
+	if code != 0 {
+		t.Fatalf("exit code %d:\n%s", code, stderr)
+	}
+	for _, want := range []string{"Creating Network net-a (9f8e7d6c-0000-4000-8000-000000000001): running 40%", "Created Network net-a (9f8e7d6c-0000-4000-8000-000000000001): success 100%"} {
+		if !strings.Contains(stderr, want) {
+			t.Errorf("stderr = %q, want %q", stderr, want)
+		}
+	}
+}
+
+func TestReplayCreateVdcNetworkTaskError(t *testing.T) {
+	_, stderr, code := replayVcdctl(t, "create-vdc-network-error", "create", "vdc-network", "net-a", "--orgvdc", "vdc-a", "--type", "ISOLATED", "--cidr", "192.168.10.1/24", "--wait")
+	if code != ExitError {
+		t.Errorf("exit code = %d, want %d", code, ExitError)
+	}
+	if want := "error: The subnet 192.168.10.0/24 overlaps with network net-b."; !strings.Contains(stderr, want) {
+		t.Errorf("stderr = %q, want %q", stderr, want)
+	}
+}
+
+func TestReplaySetPowerOnWait(t *testing.T) {
+	_, stderr, code := replayVcdctl(t, "set-power-on", "set", "power", "on", "web", "--wait")
+	if code != 0 {
+		t.Fatalf("exit code %d:\n%s", code, stderr)
+	}
+	if want := "success 100%"; !strings.Contains(stderr, want) {
+		t.Errorf("stderr = %q, want %q", stderr, want)
+	}
+}
+
+func TestReplayUnrecordedRequest(t *testing.T) {
+	_, stderr, code := replayVcdctl(t, "create-vdc-network", "get", "vapp", "nothing")
+	if code != ExitError || !strings.Contains(stderr, "no recorded response") {
+		t.Errorf("exit code = %d, stderr = %q", code, stderr)
+	}
+}
@@ -0,0 +1,262 @@
+package module
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+var requestMethods = []string{"GET", "POST", "PUT", "PATCH", "DELETE"}
+
+// RawRequest is a request given on the command line, sent as is by the raw
+// commands (get, post, put, patch, delete and request).
+type RawRequest struct {
+	Method string
+	Path   string
+	// "Name: value"
+	Headers []string
+	// "key=value", added to the query string of the path
+	Query    []string
+	FileName string
+	Data     string
+	// Content-Type of the body, inferred from the api and the body when empty
+	ContentType string
+	// renders the body with --set and --values
+	Template BodyTemplate
+	// print the status line and the response headers as well
+	Include bool
+	// exit with an error on a http error, instead of printing the response
+	Fail bool
+	// how the response body is printed
+	Format ResponseFormat
+}
+
+// addRawRequestFlags adds the flags of the raw request. They are local
+// flags, the subcommands of get and delete have their own.
+func addRawRequestFlags(cmd *cobra.Command, r *RawRequest, withBody bool) {
+	cmd.Flags().StringArrayVarP(&r.Headers, "header", "H", nil, "additional header, repeatable (e.g. -H 'Content-Type: application/vnd.vmware.vcloud.vm+xml')")
+	cmd.Flags().StringArrayVar(&r.Query, "query", nil, "query parameter key=value, url encoded and repeatable (e.g. --query 'filter=name==web*')")
+	cmd.Flags().BoolVarP(&r.Include, "include", "i", false, "print the status line and the response headers")
+	cmd.Flags().BoolVar(&r.Fail, "fail", false, "exit non-zero on http errors, without printing the response")
+	cmd.Flags().StringVar(&r.Format.To, "to", "", "convert the response, json converts a xml response (attributes as \"@name\", text as \"#text\", child elements as arrays)")
+	cmd.Flags().StringVar(&r.Format.Select, "select", "", "XPath of a xml response (e.g. '//VAppRecord/@name') or JSONPath of a json one (e.g. '{.values[*].name}')")
+	cmd.Flags().BoolVar(&r.Format.AllPages, "all-pages", false, "get all the pages of a query or a /cloudapi collection and merge the records or values")
+	if withBody {
+		cmd.Flags().StringVarP(&r.FileName, "filename", "f", "", "file of the request body, - for stdin")
+		cmd.Flags().StringVarP(&r.Data, "data", "d", "", "request body")
+		cmd.Flags().StringVar(&r.ContentType, "content-type", "", "Content-Type of the body (default: inferred from the api and the xml root element, json for /cloudapi)")
+		cmd.Flags().StringArrayVar(&r.Template.Set, "set", nil, "template variable key=value of the body, repeatable (the body is a go template with --set, --values or a .tmpl file)\n"+
+			"  lookups: {{urn \"vdc\" .vdc}} {{href \"vapp\" \"web01\"}} {{uuid \"org\" .org}} {{id \"edge\" \"vdc/edge\"}} {{(ref \"vapp\" .name).Name}}")
+		cmd.Flags().StringVar(&r.Template.ValuesFile, "values", "", "yaml file of template variables of the body, overridden by --set")
+	}
+}
+
+// Do sends the request and prints the response. A response with a http
+// error is printed like the others, unless Fail is set. With --wait, it
+// waits for the task started by a POST, PUT, PATCH or DELETE.
+func (r *RawRequest) Do() {
+	res, err := r.Send()
+	var apiError *ApiError
+	if err != nil && (res == nil || !errors.As(err, &apiError) || r.Fail) {
+		Fatal(err)
+	}
+	r.Print(res)
+	if err == nil && r.Method != "GET" {
+		if err := waitResponseTask(res); err != nil {
+			Fatal(err)
+		}
+	}
+}
+
+// Send sends the request. On a http error, the response is returned with
+// the ApiError.
+func (r *RawRequest) Send() (*Response, error) {
+	if err := r.Format.validate(r.Method); err != nil {
+		return nil, err
+	}
+	path, err := r.url()
+	if err != nil {
+		return nil, err
+	}
+	body, err := r.body()
+	if err != nil {
+		return nil, err
+	}
+	header, err := r.header(path, body)
+	if err != nil {
+		return nil, err
+	}
+	res, err := client.Request(r.Method, path, header, body)
+	if err != nil || !r.Format.AllPages {
+		return res, err
+	}
+	return getAllPages(path, header, res)
+}
+
+func (r *RawRequest) url() (string, error) {
+	path, err := expandPath(r.Path)
+	if err != nil {
+		return "", err
+	}
+	if len(r.Query) == 0 {
+		return path, nil
+	}
+	values := url.Values{}
+	for _, kv := range r.Query {
+		key, value, ok := strings.Cut(kv, "=")
+		if !ok || key == "" {
+			return "", fmt.Errorf("invalid query parameter \"%s\", expected key=value", kv)
+		}
+		values.Add(key, value)
+	}
+	// the query given in the path is kept as is
+	sep := "?"
+	if strings.Contains(path, "?") {
+		sep = "&"
+	}
+	return path + sep + values.Encode(), nil
+}
+
+func (r *RawRequest) header(path string, body []byte) (map[string]string, error) {
+	header := map[string]string{}
+	for _, h := range r.Headers {
+		// split on the first colon only, values may contain ": " as well
+		name, value, ok := strings.Cut(h, ":")
+		name = strings.TrimSpace(name)
+		if !ok || name == "" {
+			return nil, fmt.Errorf("invalid header \"%s\", expected 'Name: value'", h)
+		}
+		header[http.CanonicalHeaderKey(name)] = strings.TrimSpace(value)
+	}
+
+	if r.ContentType != "" {
+		header["Content-Type"] = r.ContentType
+	} else if _, ok := header["Content-Type"]; !ok {
+		if contentType := inferContentType(r.Method, path, body); contentType != "" {
+			Log("Content-Type: " + contentType)
+			header["Content-Type"] = contentType
+		}
+	}
+	return header, nil
+}
+
+func (r *RawRequest) body() ([]byte, error) {
+	if r.Data != "" && r.FileName != "" {
+		return nil, errors.New("--data and --filename can not be used together")
+	}
+	var body []byte
+	name := "data"
+	if r.Data != "" {
+		body = []byte(r.Data)
+	} else if r.FileName != "" {
+		var err error
+		if body, err = ReadRequestData(r.FileName); err != nil {
+			return nil, err
+		}
+		name = r.FileName
+	}
+	if r.Template.Enabled(r.FileName) {
+		return r.Template.Render(name, body)
+	}
+	return body, nil
+}
+
+func (r *RawRequest) Print(res *Response) {
+	if r.Include {
+		fmt.Printf("%s %s\n", res.Proto, res.Status)
+		names := []string{}
+		for name := range res.Header {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			for _, value := range res.Header[name] {
+				fmt.Printf("%s: %s\n", name, value)
+			}
+		}
+		fmt.Println()
+	}
+	body, err := r.Format.Format(res)
+	if err != nil {
+		Fatal(err)
+	}
+	fmt.Println(body)
+}
+
+// rawApiExample is the help of the api argument of the raw commands.
+const rawApiExample = `  the api may refer to objects by name, resolved before the request:
+    {vapp:NAME} {vdc:NAME} {org:NAME} {edge:VDC_NAME/NAME}
+  an ambiguous name fails, qualify it as {vapp:VDC_NAME/NAME} or {vdc:ORG_NAME/NAME}
+  replaced by the urn in /cloudapi paths and by the id in /api paths, or by
+  the form given as {vapp.urn:NAME}, {vapp.uuid:NAME}, {vapp.id:NAME} or {vapp.href:NAME}
+  e.g. /api/vApp/{vapp:web01}/power/action/powerOn`
+
+// newCmdRaw returns the command sending the method to the api given as the
+// argument.
+func newCmdRaw(method string) *cobra.Command {
+	r := &RawRequest{Method: method}
+	cmd := &cobra.Command{
+		Use:   strings.ToLower(method) + " ${API}",
+		Short: "exec " + strings.ToLower(method) + " api",
+		Long:  "exec " + strings.ToLower(method) + " api\n\n" + rawApiExample,
+		Args:  cobra.ExactArgs(1),
+		PersistentPreRun: func(cmd *cobra.Command, args []string) {
+			initClient()
+		},
+		Run: func(cmd *cobra.Command, args []string) {
+			r.Path = args[0]
+			r.Do()
+		},
+	}
+	if method != "GET" {
+		addWaitFlags(cmd)
+	}
+	addRawRequestFlags(cmd, r, method != "GET")
+	return cmd
+}
+
+func NewCmdPatch() *cobra.Command {
+	return newCmdRaw("PATCH")
+}
+
+func NewCmdRequest() *cobra.Command {
+	r := &RawRequest{}
+	cmd := &cobra.Command{
+		Use:   "request ${METHOD} ${API}",
+		Short: "exec api with any method",
+		Long:  "exec api with any method\n\n" + rawApiExample,
+		Args:  cobra.ExactArgs(2),
+		PersistentPreRun: func(cmd *cobra.Command, args []string) {
+			initClient()
+		},
+		ValidArgsFunction: func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+			if len(args) != 0 {
+				return nil, cobra.ShellCompDirectiveNoFileComp
+			}
+			return requestMethods, cobra.ShellCompDirectiveNoFileComp
+		},
+		Run: func(cmd *cobra.Command, args []string) {
+			r.Method = strings.ToUpper(args[0])
+			r.Path = args[1]
+			r.Do()
+		},
+	}
+	addWaitFlags(cmd)
+	addRawRequestFlags(cmd, r, true)
+	return cmd
+}
+
+// ReadRequestData reads the request body from the file, or stdin for "-".
+func ReadRequestData(fileName string) ([]byte, error) {
+	if fileName == "-" {
+		return io.ReadAll(os.Stdin)
+	}
+	return os.ReadFile(fileName)
+}
@@ -0,0 +1,161 @@
+package module
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// ObjectRef is an object resolved by name, in the forms the apis use.
+type ObjectRef struct {
+	Name string
+	// e.g. urn:vcloud:vapp:<uuid>, used by /cloudapi
+	Urn string
+	// the last part of the urn
+	Uuid string
+	// the id in /api paths, e.g. vapp-<uuid> for a vApp, else the uuid
+	Id   string
+	Href string
+}
+
+// Get returns the form ("urn", "uuid", "id" or "href") of the reference. It
+// fails when the object has no such form, e.g. the href of an edge.
+func (r ObjectRef) Get(form string) (string, error) {
+	if err := checkRefForm(form); err != nil {
+		return "", err
+	}
+	value := map[string]string{"urn": r.Urn, "uuid": r.Uuid, "id": r.Id, "href": r.Href}[form]
+	if value == "" {
+		return "", fmt.Errorf("'%s' has no %s", r.Name, form)
+	}
+	return value, nil
+}
+
+func checkRefForm(form string) error {
+	switch form {
+	case "urn", "uuid", "id", "href":
+		return nil
+	}
+	return fmt.Errorf("unknown form '%s', expected urn, uuid, id or href", form)
+}
+
+var refKinds = []string{"vapp", "vdc", "org", "edge"}
+
+// ResolveRef finds the object of the kind by the name. A vApp may be given
+// as "${VDC_NAME}/${VAPP_NAME}" and a vdc as "${ORG_NAME}/${VDC_NAME}", and
+// must be when the name alone is ambiguous. An edge is given as
+// "${VDC_NAME}/${EDGE_NAME}", or by the name in the org vdc of the context.
+func ResolveRef(kind string, name string) (ObjectRef, error) {
+	switch kind {
+	case "vapp":
+		vapp, err := resolveVApp(name)
+		if err != nil {
+			return ObjectRef{}, err
+		}
+		uuid := strings.TrimPrefix(vapp.Id, "vapp-")
+		return ObjectRef{Name: vapp.Name, Urn: "urn:vcloud:vapp:" + uuid, Uuid: uuid, Id: vapp.Id, Href: vapp.Href}, nil
+	case "vdc":
+		vdc, err := GetVdc(name)
+		if err != nil {
+			return ObjectRef{}, err
+		}
+		return ObjectRef{Name: vdc.Name, Urn: "urn:vcloud:vdc:" + vdc.Id, Uuid: vdc.Id, Id: vdc.Id, Href: vdc.Href}, nil
+	case "org":
+		org, err := GetOrg(name)
+		if err != nil {
+			return ObjectRef{}, err
+		}
+		return ObjectRef{Name: org.Name, Urn: "urn:vcloud:org:" + org.Id, Uuid: org.Id, Id: org.Id, Href: org.Href}, nil
+	case "edge":
+		vdcName, edgeName, ok := strings.Cut(name, "/")
+		if !ok {
+			vdcName, edgeName = orgVdcOrContext(""), name
+		}
+		edge, err := GetEdge(edgeName, vdcName)
+		if err != nil {
+			return ObjectRef{}, err
+		}
+		// the CloudAPI returns no href of the edges
+		uuid := LastOne(edge.Urn, ":")
+		return ObjectRef{Name: edge.Name, Urn: edge.Urn, Uuid: uuid, Id: uuid}, nil
+	}
+	return ObjectRef{}, fmt.Errorf("unknown kind '%s', expected one of %s", kind, strings.Join(refKinds, ", "))
+}
+
+// resolveVApp finds the vApp by the name, unique in the vdc when given, or
+// by the id. The query asks for two records to tell an ambiguous name: the
+// object of a raw DELETE must not be the first one by chance.
+func resolveVApp(name string) (VApp, error) {
+	filter := fiqlEq("name", name)
+	vdcName, vappName, qualified := strings.Cut(name, "/")
+	if qualified {
+		filter = andFilter(fiqlEq("vdcName", vdcName), fiqlEq("name", vappName))
+	}
+	vapps, err := QueryAllRecords[VApp]("vApp", Query{Filter: filter, Limit: 2})
+	if err != nil {
+		return VApp{}, err
+	}
+	switch {
+	case len(vapps) == 0 && qualified:
+		return VApp{}, &NotFoundError{Kind: "vApp", Name: vappName, Parent: vdcName}
+	case len(vapps) == 0:
+		return GetVAppByNameOrId(name, false)
+	case len(vapps) > 1 && qualified:
+		return VApp{}, fmt.Errorf("vApp name '%s' is ambiguous in the vdc '%s'", vappName, vdcName)
+	case len(vapps) > 1:
+		return VApp{}, fmt.Errorf("vApp name '%s' is ambiguous, give the vdc as {vapp:${VDC_NAME}/%s}", name, name)
+	}
+	vapp := vapps[0]
+	vapp.Id = LastOne(vapp.Href, "/")
+	return vapp, nil
+}
+
+// {kind:name} or {kind.form:name}, e.g. {vapp:web01} or {edge.urn:prod-vdc/edge01}
+var refTokenPattern = regexp.MustCompile(`\{([a-z]+)(?:\.([a-z]+))?:([^{}]+)\}`)
+
+// expandPath replaces the {kind:name} tokens of the path with the ids of the
+// objects: the urn in /cloudapi paths, the id of /api paths otherwise.
+// e.g. /api/vApp/{vapp:web01}/power/action/powerOn
+func expandPath(path string) (string, error) {
+	defaultForm := "id"
+	if strings.HasPrefix(path, "/cloudapi") {
+		defaultForm = "urn"
+	}
+	return expandRefs(path, defaultForm)
+}
+
+func expandRefs(s string, defaultForm string) (string, error) {
+	var expandErr error
+	resolved := map[string]string{}
+	expanded := refTokenPattern.ReplaceAllStringFunc(s, func(token string) string {
+		if expandErr != nil {
+			return token
+		}
+		if value, ok := resolved[token]; ok {
+			return value
+		}
+		m := refTokenPattern.FindStringSubmatch(token)
+		kind, form, name := m[1], m[2], m[3]
+		if form == "" {
+			form = defaultForm
+		}
+		// a typo in the form fails without looking up the object
+		if err := checkRefForm(form); err != nil {
+			expandErr = fmt.Errorf("%s: %w", token, err)
+			return token
+		}
+		ref, err := ResolveRef(kind, name)
+		if err != nil {
+			expandErr = fmt.Errorf("%s: %w", token, err)
+			return token
+		}
+		value, err := ref.Get(form)
+		if err != nil {
+			expandErr = fmt.Errorf("%s: %w", token, err)
+			return token
+		}
+		resolved[token] = value
+		return value
+	})
+	return expanded, expandErr
+}
@@ -0,0 +1,91 @@
+package module
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestObjectRefGet(t *testing.T) {
+	ref := ObjectRef{Name: "web01", Urn: "urn:vcloud:vapp:1", Uuid: "1", Id: "vapp-1", Href: "https://vcd.example.com/api/vApp/vapp-1"}
+	edge := ObjectRef{Name: "edge01", Urn: "urn:vcloud:gateway:2", Uuid: "2", Id: "2"}
+	tests := []struct {
+		ref  ObjectRef
+		form string
+		want string
+		err  string
+	}{
+		{ref, "urn", "urn:vcloud:vapp:1", ""},
+		{ref, "uuid", "1", ""},
+		{ref, "id", "vapp-1", ""},
+		{ref, "href", "https://vcd.example.com/api/vApp/vapp-1", ""},
+		{ref, "name", "", "unknown form 'name'"},
+		{edge, "urn", "urn:vcloud:gateway:2", ""},
+		{edge, "href", "", "'edge01' has no href"},
+	}
+	for _, tt := range tests {
+		got, err := tt.ref.Get(tt.form)
+		if tt.err != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.err) {
+				t.Errorf("Get(%q) of %s = %q, %v, want error %q", tt.form, tt.ref.Name, got, err, tt.err)
+			}
+			continue
+		}
+		if err != nil || got != tt.want {
+			t.Errorf("Get(%q) of %s = %q, %v, want %q", tt.form, tt.ref.Name, got, err, tt.want)
+		}
+	}
+}
+
+func TestExpandPath(t *testing.T) {
+	connectReplay(t, "resolve")
+	tests := []struct {
+		path string
+		want string
+		err  string
+	}{
+		{"/api/org", "/api/org", ""},
+		// the id in /api paths, the urn in /cloudapi paths
+		{"/api/vApp/{vapp:web01}/owner", "/api/vApp/vapp-3c4d5e6f-0000-4000-8000-000000000001/owner", ""},
+		{"/cloudapi/1.0.0/vapps/{vapp:web01}", "/cloudapi/1.0.0/vapps/urn:vcloud:vapp:3c4d5e6f-0000-4000-8000-000000000001", ""},
+		{"/api/vApp/{vapp:web01}/{vapp.uuid:web01}", "/api/vApp/vapp-3c4d5e6f-0000-4000-8000-000000000001/3c4d5e6f-0000-4000-8000-000000000001", ""},
+		// an ambiguous name, and the qualified one
+		{"/api/vApp/{vapp:web02}", "", "vApp name 'web02' is ambiguous, give the vdc as {vapp:${VDC_NAME}/web02}"},
+		{"/api/vApp/{vapp:vdc-b/web02}", "/api/vApp/vapp-3c4d5e6f-0000-4000-8000-000000000003", ""},
+		{"/api/vApp/{vapp:vdc-b/web03}", "", `vApp "web03" not found at vdc-b`},
+		{"/api/admin/vdc/{vdc:vdc-a}", "", "Org VDC name 'vdc-a' is ambiguous, give the org as ${ORG_NAME}/vdc-a"},
+		{"/api/admin/vdc/{vdc:tenant-b/vdc-a}", "/api/admin/vdc/5e6f7a8b-0000-4000-8000-0000000000b1", ""},
+		{"/cloudapi/1.0.0/vdcs/{vdc:vdc-b}", "/cloudapi/1.0.0/vdcs/urn:vcloud:vdc:5e6f7a8b-0000-4000-8000-0000000000a2", ""},
+		{"/api/admin/vdc/{vdc:tenant-b/vdc-b}", "", `Org VDC "vdc-b" not found at tenant-b`},
+		{"/cloudapi/1.0.0/edgeGateways/{edge:vdc-b/edge01}", "/cloudapi/1.0.0/edgeGateways/urn:vcloud:gateway:7a8b9c0d-0000-4000-8000-000000000001", ""},
+		{"/api/x/{edge.href:vdc-b/edge01}", "", "'edge01' has no href"},
+		// not looked up
+		{"/api/vApp/{vapp.name:web01}", "", "unknown form 'name'"},
+		{"/api/x/{network:net-a}", "", "unknown kind 'network'"},
+	}
+	for _, tt := range tests {
+		got, err := expandPath(tt.path)
+		if tt.err != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.err) {
+				t.Errorf("expandPath(%q) = %q, %v, want error %q", tt.path, got, err, tt.err)
+			}
+			continue
+		}
+		if err != nil || got != tt.want {
+			t.Errorf("expandPath(%q) = %q, %v, want %q", tt.path, got, err, tt.want)
+		}
+	}
+}
+
+func TestReplayRawGetAmbiguousVApp(t *testing.T) {
+	_, stderr, code := replayVcdctl(t, "resolve", "get", "/api/vApp/{vapp:web02}")
+	if code != ExitError || !strings.Contains(stderr, "vApp name 'web02' is ambiguous") {
+		t.Errorf("exit code = %d, stderr = %q", code, stderr)
+	}
+}
+
+func TestReplayRawGetQualifiedVdc(t *testing.T) {
+	got := runReplay(t, "resolve", "get", "/api/admin/vdc/{vdc:tenant-b/vdc-a}", "--select", "/AdminVdc/@name")
+	if got != "vdc-a\n" {
+		t.Errorf("get /api/admin/vdc/{vdc:tenant-b/vdc-a} = %q", got)
+	}
+}
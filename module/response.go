@@ -0,0 +1,245 @@
+package module
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// ResponseFormat is how the raw commands print the response body. The json
+// and xml bodies are indented, other ones are printed as is.
+type ResponseFormat struct {
+	// "json" converts a xml response to json
+	To string
+	// XPath of a xml response, JSONPath of a json one
+	Select string
+	// get all the pages of a query or a /cloudapi collection
+	AllPages bool
+}
+
+func (f *ResponseFormat) validate(method string) error {
+	if f.To != "" && f.To != "json" {
+		return fmt.Errorf("unknown --to '%s', expected json", f.To)
+	}
+	if f.AllPages && method != "GET" {
+		return fmt.Errorf("--all-pages can not be used with %s", method)
+	}
+	return nil
+}
+
+// responseKind tells "json" or "xml" by the Content-Type of the response, by
+// the body when there is none.
+func responseKind(res *Response) string {
+	mediaType, _, _ := strings.Cut(http.Header(res.Header).Get("Content-Type"), ";")
+	mediaType = strings.TrimSpace(mediaType)
+	switch {
+	case strings.HasSuffix(mediaType, "json"):
+		return "json"
+	case strings.HasSuffix(mediaType, "xml"):
+		return "xml"
+	case mediaType != "":
+		return ""
+	}
+	trimmed := bytes.TrimSpace(res.Body)
+	if len(trimmed) == 0 {
+		return ""
+	}
+	switch trimmed[0] {
+	case '{', '[':
+		return "json"
+	case '<':
+		return "xml"
+	}
+	return ""
+}
+
+// Format returns the body to print. A JSONPath (starting with { or .) on a
+// xml response selects from the response converted to json.
+func (f *ResponseFormat) Format(res *Response) (string, error) {
+	isJSONPath := strings.HasPrefix(f.Select, "{") || strings.HasPrefix(f.Select, ".")
+	switch responseKind(res) {
+	case "xml":
+		doc, err := parseXmlDocument(res.Body)
+		if err != nil {
+			if f.To == "" && f.Select == "" {
+				return string(res.Body), nil
+			}
+			return "", err
+		}
+		if f.To == "json" || isJSONPath {
+			return f.formatJson(doc.ToJSON())
+		}
+		if f.Select != "" {
+			values, err := doc.SelectXPath(f.Select)
+			return strings.Join(values, "\n"), err
+		}
+		return doc.String(), nil
+	case "json":
+		if f.Select == "" {
+			var buf bytes.Buffer
+			if err := json.Indent(&buf, bytes.TrimSpace(res.Body), "", "  "); err != nil {
+				return string(res.Body), nil
+			}
+			return buf.String(), nil
+		}
+		var data any
+		if err := json.Unmarshal(res.Body, &data); err != nil {
+			return "", err
+		}
+		return f.formatJson(data)
+	}
+	if f.To != "" || f.Select != "" {
+		return "", fmt.Errorf("can not convert or select the response of Content-Type '%s'", http.Header(res.Header).Get("Content-Type"))
+	}
+	return string(res.Body), nil
+}
+
+func (f *ResponseFormat) formatJson(data any) (string, error) {
+	if f.Select == "" {
+		return strings.TrimSuffix(string(marshalJson(data)), "\n"), nil
+	}
+	// {.values[*].name} or .values[*].name
+	expr := f.Select
+	if !strings.Contains(expr, "{") {
+		expr = "{" + expr + "}"
+	}
+	jp, err := ParseJSONPath(expr)
+	if err != nil {
+		return "", err
+	}
+	return jp.Execute(data)
+}
+
+// getAllPages gets the pages following the response, and returns the
+// response with them merged: the values of a /cloudapi collection (up to
+// pageCount), or the records of a query (following the nextPage links).
+func getAllPages(path string, header map[string]string, first *Response) (*Response, error) {
+	var body []byte
+	var err error
+	switch responseKind(first) {
+	case "json":
+		body, err = getAllCloudApiPages(path, header, first)
+	case "xml":
+		body, err = getAllRecordsPages(header, first)
+	}
+	if err != nil {
+		// not the first page printed as if it was all of them
+		return nil, err
+	}
+	if body == nil {
+		return first, nil
+	}
+	merged := *first
+	merged.Body = body
+	return &merged, nil
+}
+
+// getAllCloudApiPages returns the first page with the values of all the
+// pages, or nil when the response is not a page.
+func getAllCloudApiPages(path string, header map[string]string, first *Response) ([]byte, error) {
+	var page cloudApiPage[json.RawMessage]
+	fields := map[string]json.RawMessage{}
+	if json.Unmarshal(first.Body, &page) != nil || json.Unmarshal(first.Body, &fields) != nil || page.PageCount <= 1 {
+		return nil, nil
+	}
+	values := page.Values
+	for len(page.Values) > 0 && page.Page < page.PageCount {
+		api, err := pageApi(path, page.Page+1)
+		if err != nil {
+			return nil, err
+		}
+		res, err := client.Request("GET", api, header, nil)
+		if err != nil {
+			return nil, err
+		}
+		page = cloudApiPage[json.RawMessage]{}
+		if err := json.Unmarshal(res.Body, &page); err != nil {
+			return nil, err
+		}
+		values = append(values, page.Values...)
+	}
+	// the result is not a page anymore
+	delete(fields, "page")
+	delete(fields, "pageSize")
+	delete(fields, "pageCount")
+	fields["values"] = marshalJson(values)
+	return marshalJson(fields), nil
+}
+
+// pageApi sets the page parameter of the query string of the api.
+func pageApi(path string, page int) (string, error) {
+	u, err := url.Parse(path)
+	if err != nil {
+		return "", err
+	}
+	q := u.Query()
+	q.Set("page", strconv.Itoa(page))
+	u.RawQuery = q.Encode()
+	return u.RequestURI(), nil
+}
+
+// the links of a page of records, dropped from the merged records
+var pageLinkRels = map[string]bool{"firstPage": true, "previousPage": true, "nextPage": true, "lastPage": true}
+
+// getAllRecordsPages returns the first page with the records of all the
+// pages, or nil when there is no next page.
+func getAllRecordsPages(header map[string]string, first *Response) ([]byte, error) {
+	doc, err := parseXmlDocument(first.Body)
+	if err != nil {
+		return nil, nil
+	}
+	records, links := splitPageLinks(doc.Root.Children)
+	api := nextPageApi(first, links)
+	if api == "" {
+		return nil, nil
+	}
+	for api != "" {
+		res, err := client.Request("GET", api, header, nil)
+		if err != nil {
+			return nil, err
+		}
+		next, err := parseXmlDocument(res.Body)
+		if err != nil {
+			return nil, err
+		}
+		var nextRecords []*xmlNode
+		nextRecords, links = splitPageLinks(next.Root.Children)
+		for _, c := range nextRecords {
+			// the other links are the same as the ones of the first page
+			if localName(c.Name) != "Link" {
+				records = append(records, c)
+			}
+		}
+		api = nextPageApi(res, links)
+	}
+	doc.Root.Children = records
+	attrs := doc.Root.Attrs[:0]
+	for _, attr := range doc.Root.Attrs {
+		if attr.Name.Local != "page" && attr.Name.Local != "pageSize" {
+			attrs = append(attrs, attr)
+		}
+	}
+	doc.Root.Attrs = attrs
+	return []byte(doc.String()), nil
+}
+
+// splitPageLinks separates the paging links of a page from the other
+// elements, the records and the other links.
+func splitPageLinks(children []*xmlNode) ([]*xmlNode, []Link) {
+	records := []*xmlNode{}
+	links := []Link{}
+	for _, c := range children {
+		rel, _ := c.attr("rel")
+		if localName(c.Name) == "Link" && pageLinkRels[rel] {
+			href, _ := c.attr("href")
+			links = append(links, Link{Rel: rel, Href: href})
+			continue
+		}
+		records = append(records, c)
+	}
+	return records, links
+}
@@ -0,0 +1,114 @@
+package module
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestResponseKind(t *testing.T) {
+	tests := []struct {
+		contentType string
+		body        string
+		want        string
+	}{
+		{"application/vnd.vmware.vcloud.vApp+xml;version=37.0", `{}`, "xml"},
+		{"application/json", `<a/>`, "json"},
+		{"application/*+json", ``, "json"},
+		{"text/plain", `<a/>`, ""},
+		{"", ` {"a":1}`, "json"},
+		{"", `[1]`, "json"},
+		{"", "\n<a/>", "xml"},
+		{"", `ok`, ""},
+		{"", ``, ""},
+	}
+	for _, tt := range tests {
+		res := &Response{Header: map[string][]string{}, Body: []byte(tt.body)}
+		if tt.contentType != "" {
+			res.Header["Content-Type"] = []string{tt.contentType}
+		}
+		if got := responseKind(res); got != tt.want {
+			t.Errorf("responseKind(%q, %q) = %q, want %q", tt.contentType, tt.body, got, tt.want)
+		}
+	}
+}
+
+func TestResponseFormat(t *testing.T) {
+	const records = `<QueryResultRecords total="2"><VAppRecord name="web01" status="POWERED_ON"/><VAppRecord name="db01" status="POWERED_OFF"/></QueryResultRecords>`
+	const values = `{"values":[{"name":"web01","id":"1"},{"name":"db01","id":"2"}]}`
+	tests := []struct {
+		format      ResponseFormat
+		contentType string
+		body        string
+		want        string
+	}{
+		// pretty printed
+		{ResponseFormat{}, "application/vnd.vmware.vcloud.query.records+xml", `<a><b>x</b></a>`, "<a>\n  <b>x</b>\n</a>"},
+		{ResponseFormat{}, "application/json", `{"a":[1]}`, "{\n  \"a\": [\n    1\n  ]\n}"},
+		{ResponseFormat{}, "", `<a><b>x</b></a>`, "<a>\n  <b>x</b>\n</a>"},
+		// printed as is
+		{ResponseFormat{}, "text/plain", `<a><b>x</b></a>`, `<a><b>x</b></a>`},
+		{ResponseFormat{}, "application/xml", `<a>`, `<a>`},
+		{ResponseFormat{}, "application/json", `{"a":`, `{"a":`},
+		// converted and selected
+		{ResponseFormat{To: "json"}, "application/xml", `<a n="1"><b>x</b></a>`, "{\n  \"a\": {\n    \"@n\": \"1\",\n    \"b\": [\n      \"x\"\n    ]\n  }\n}"},
+		{ResponseFormat{Select: "//VAppRecord/@name"}, "application/xml", records, "web01\ndb01"},
+		{ResponseFormat{Select: "//VAppRecord[@status='POWERED_OFF']/@name"}, "application/xml", records, "db01"},
+		{ResponseFormat{Select: ".QueryResultRecords.VAppRecord[*].@name"}, "application/xml", records, "web01 db01"},
+		{ResponseFormat{Select: "{.QueryResultRecords.@total}"}, "application/xml", records, "2"},
+		{ResponseFormat{Select: ".values[*].name"}, "application/json", values, "web01 db01"},
+		{ResponseFormat{Select: `{range .values[*]}{.id}={.name}{"\n"}{end}`}, "application/json", values, "1=web01\n2=db01\n"},
+	}
+	for _, tt := range tests {
+		res := &Response{Header: map[string][]string{}, Body: []byte(tt.body)}
+		if tt.contentType != "" {
+			res.Header["Content-Type"] = []string{tt.contentType}
+		}
+		got, err := tt.format.Format(res)
+		if err != nil {
+			t.Errorf("Format(%+v, %q): %v", tt.format, tt.body, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("Format(%+v, %q) =\n%s\nwant\n%s", tt.format, tt.body, got, tt.want)
+		}
+	}
+}
+
+func TestResponseFormatErrors(t *testing.T) {
+	tests := []struct {
+		format      ResponseFormat
+		contentType string
+		body        string
+		want        string
+	}{
+		{ResponseFormat{To: "json"}, "text/plain", `ok`, "can not convert or select"},
+		{ResponseFormat{Select: "//a"}, "", `ok`, "can not convert or select"},
+		{ResponseFormat{To: "json"}, "application/xml", `<a>`, "not closed"},
+		{ResponseFormat{Select: ".a"}, "application/json", `{"a":`, "unexpected end"},
+		{ResponseFormat{Select: "//a[@b=c]"}, "application/xml", `<a/>`, "quoted value"},
+	}
+	for _, tt := range tests {
+		res := &Response{Header: map[string][]string{}, Body: []byte(tt.body)}
+		if tt.contentType != "" {
+			res.Header["Content-Type"] = []string{tt.contentType}
+		}
+		_, err := tt.format.Format(res)
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("Format(%+v, %q) error = %v, want %q", tt.format, tt.body, err, tt.want)
+		}
+	}
+
+	if err := (&ResponseFormat{To: "yaml"}).validate("GET"); err == nil {
+		t.Error("--to yaml: want an error")
+	}
+	if err := (&ResponseFormat{AllPages: true}).validate("POST"); err == nil {
+		t.Error("--all-pages with POST: want an error")
+	}
+}
+
+func TestReplayRawGet(t *testing.T) {
+	got := runReplay(t, "get-org", "get", "/api/org", "--select", "//Org/@name")
+	if got != "tenant-b\ntenant-a\n" {
+		t.Errorf("get /api/org --select = %q", got)
+	}
+}
@@ -0,0 +1,159 @@
+package module
+
+import (
+	"errors"
+	"io"
+	"math/rand"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"syscall"
+	"time"
+)
+
+const defaultRequestTimeout = 30 * time.Second
+
+// set by the global flags
+var (
+	requestTimeout time.Duration
+	maxRetries     = 3
+	retryPost      bool
+	rateLimit      float64
+)
+
+// RetryPolicy decides which failed requests are sent again and when.
+type RetryPolicy struct {
+	MaxRetries int
+	// POST and PATCH are not idempotent, they are retried only when enabled
+	RetryPost bool
+	BaseDelay time.Duration
+	MaxDelay  time.Duration
+}
+
+func defaultRetryPolicy() RetryPolicy {
+	return RetryPolicy{
+		MaxRetries: maxRetries,
+		RetryPost:  retryPost,
+		BaseDelay:  time.Second,
+		MaxDelay:   30 * time.Second,
+	}
+}
+
+// ShouldRetry reports whether the attempt (0 for the first one) failed
+// transiently: a network error, 429, or 502/503/504 during cell failover.
+func (p RetryPolicy) ShouldRetry(method string, res *http.Response, err error, attempt int) bool {
+	if attempt >= p.MaxRetries {
+		return false
+	}
+	switch method {
+	case http.MethodPost, http.MethodPatch:
+		if !p.RetryPost {
+			return false
+		}
+	}
+	if err != nil {
+		return isTransientNetworkError(err)
+	}
+	switch res.StatusCode {
+	case http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
+
+// isTransientNetworkError excludes the errors which fail again, e.g. tls
+// verification or dns errors.
+func isTransientNetworkError(err error) bool {
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return true
+	}
+	return errors.Is(err, syscall.ECONNRESET) ||
+		errors.Is(err, syscall.ECONNREFUSED) ||
+		errors.Is(err, io.EOF) ||
+		errors.Is(err, io.ErrUnexpectedEOF)
+}
+
+// Delay returns how long to wait before the next attempt: Retry-After when
+// the server sent it, else exponential backoff with jitter.
+func (p RetryPolicy) Delay(res *http.Response, attempt int) time.Duration {
+	if res != nil {
+		if delay, ok := parseRetryAfter(res.Header.Get("Retry-After")); ok {
+			if delay > p.MaxDelay {
+				delay = p.MaxDelay
+			}
+			return delay
+		}
+	}
+	delay := p.BaseDelay << attempt
+	if delay <= 0 || delay > p.MaxDelay {
+		delay = p.MaxDelay
+	}
+	// between a half and the full delay, so that parallel clients spread out
+	return delay/2 + time.Duration(rand.Int63n(int64(delay/2)+1))
+}
+
+// parseRetryAfter accepts seconds or an http date.
+func parseRetryAfter(value string) (time.Duration, bool) {
+	if value == "" {
+		return 0, false
+	}
+	if seconds, err := strconv.Atoi(value); err == nil && seconds >= 0 {
+		return time.Duration(seconds) * time.Second, true
+	}
+	if t, err := http.ParseTime(value); err == nil {
+		delay := time.Until(t)
+		if delay < 0 {
+			delay = 0
+		}
+		return delay, true
+	}
+	return 0, false
+}
+
+// rateLimiter spaces the requests evenly to the given rate. It is safe for
+// concurrent use.
+type rateLimiter struct {
+	mu       sync.Mutex
+	interval time.Duration
+	next     time.Time
+}
+
+// newRateLimiter returns nil (no limit) when rps is not positive.
+func newRateLimiter(rps float64) *rateLimiter {
+	if rps <= 0 {
+		return nil
+	}
+	return &rateLimiter{interval: time.Duration(float64(time.Second) / rps)}
+}
+
+func (l *rateLimiter) Wait() {
+	if l == nil {
+		return
+	}
+	l.mu.Lock()
+	now := time.Now()
+	if l.next.Before(now) {
+		l.next = now
+	}
+	wait := l.next.Sub(now)
+	l.next = l.next.Add(l.interval)
+	l.mu.Unlock()
+	time.Sleep(wait)
+}
+
+// siteTimeout returns --request-timeout, the timeout of the site, or the default.
+func siteTimeout(site Site) (time.Duration, error) {
+	if requestTimeout > 0 {
+		return requestTimeout, nil
+	}
+	if site.Timeout == "" {
+		return defaultRequestTimeout, nil
+	}
+	timeout, err := time.ParseDuration(site.Timeout)
+	if err != nil {
+		return 0, err
+	}
+	return timeout, nil
+}
@@ -9,26 +9,51 @@ var (
 	config         Config
 	configFilePath string
 	isDebugMode    bool
+	siteOverride   string
 )
 
 func GetCmdRoot() *cobra.Command {
 	cmd := &cobra.Command{
 		Use:   "vcdctl",
 		Short: "vCD command-line client",
-		Long:  "vCD command-line client",
+		Long: `vCD command-line client
+
+Exit codes:
+  1  error
+  2  authentication or authorization failure
+  3  not found
+  4  conflict
+  5  validation error
+  6  server error`,
 	}
 	cmd.AddCommand(
 		NewCmdGet(),
 		NewCmdPost(),
 		NewCmdPut(),
 		NewCmdDelete(),
+		NewCmdPatch(),
+		NewCmdRequest(),
 		NewCmdConfig(),
 		NewCmdApi(),
 		NewCmdCreate(),
 		NewCmdSet(),
+		NewCmdTask(),
+		NewCmdLogout(),
 	)
 	cmd.PersistentFlags().StringVarP(&configFilePath, "config", "c", defaultConfigFilePath(), "path to vcdctl config file")
+	cmd.PersistentFlags().StringVar(&siteOverride, "site", "", "site to use instead of the current site (or VCDCTL_SITE)")
+	cmd.RegisterFlagCompletionFunc("site", func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		return completeSiteNames()
+	})
 	cmd.PersistentFlags().BoolVar(&isDebugMode, "debug", false, "for debug")
+	cmd.PersistentFlags().DurationVar(&requestTimeout, "request-timeout", 0, "timeout of each request (default: the timeout of the site, or 30s)")
+	cmd.PersistentFlags().IntVar(&maxRetries, "retries", maxRetries, "retries of requests failed with 429, 502-504 or network errors")
+	cmd.PersistentFlags().BoolVar(&retryPost, "retry-post", false, "retry POST and PATCH requests as well (they may not be idempotent)")
+	cmd.PersistentFlags().IntVar(&concurrency, "concurrency", concurrency, "max concurrent requests of the listing commands")
+	cmd.PersistentFlags().Float64Var(&rateLimit, "rate-limit", 0, "max requests per second (0 for no limit)")
+	cmd.PersistentFlags().StringVar(&apiVersionOverride, "api-version", "", "API version to use instead of the negotiated one")
+	cmd.PersistentFlags().StringVar(&recordDir, "record", "", "record the requests and responses (credentials redacted) to the directory")
+	cmd.PersistentFlags().StringVar(&replayDir, "replay", "", "answer the requests with the responses recorded by --record, without network access")
 
 	return cmd
 }
@@ -0,0 +1,240 @@
+package module
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+
+	"golang.org/x/crypto/pbkdf2"
+	"golang.org/x/term"
+)
+
+// Passwords in the config file are encrypted with AES-256-GCM, with a key
+// derived from the passphrase by PBKDF2-HMAC-SHA256. The stored value is
+// "enc:v1:" + base64(salt | nonce | ciphertext).
+const (
+	encryptedSecretPrefix = "enc:v1:"
+	secretSaltSize        = 16
+	secretKeyIterations   = 600000
+)
+
+// the passphrase is asked once per process
+var secretPassphrase string
+
+// whether secretPassphrase decrypted the stored secrets
+var secretPassphraseChecked bool
+
+// getPassphrase returns the passphrase of the encrypted store, from
+// VCDCTL_PASSPHRASE or the terminal. When confirm is true, it is asked twice.
+func getPassphrase(confirm bool) (string, error) {
+	if secretPassphrase != "" {
+		return secretPassphrase, nil
+	}
+	if passphrase := os.Getenv("VCDCTL_PASSPHRASE"); passphrase != "" {
+		secretPassphrase = passphrase
+		return passphrase, nil
+	}
+	fd := int(os.Stdin.Fd())
+	if !term.IsTerminal(fd) {
+		return "", errors.New("passphrase for the password store is required: set VCDCTL_PASSPHRASE")
+	}
+
+	passphrase, err := readPassword("Passphrase for vcdctl passwords: ")
+	if err != nil {
+		return "", err
+	}
+	if passphrase == "" {
+		return "", errors.New("passphrase must not be empty")
+	}
+	if confirm {
+		again, err := readPassword("Confirm passphrase: ")
+		if err != nil {
+			return "", err
+		}
+		if again != passphrase {
+			return "", errors.New("passphrases do not match")
+		}
+	}
+	secretPassphrase = passphrase
+	return passphrase, nil
+}
+
+func readPassword(prompt string) (string, error) {
+	fmt.Fprint(os.Stderr, prompt)
+	password, err := term.ReadPassword(int(os.Stdin.Fd()))
+	fmt.Fprintln(os.Stderr)
+	return string(password), err
+}
+
+func isEncryptedSecret(value string) bool {
+	return strings.HasPrefix(value, encryptedSecretPrefix)
+}
+
+func encryptSecret(plain string, passphrase string) (string, error) {
+	salt := make([]byte, secretSaltSize)
+	if _, err := rand.Read(salt); err != nil {
+		return "", err
+	}
+	gcm, err := newSecretCipher(passphrase, salt)
+	if err != nil {
+		return "", err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+
+	data := append(salt, nonce...)
+	data = gcm.Seal(data, nonce, []byte(plain), nil)
+	return encryptedSecretPrefix + base64.StdEncoding.EncodeToString(data), nil
+}
+
+func decryptSecret(value string, passphrase string) (string, error) {
+	data, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(value, encryptedSecretPrefix))
+	if err != nil {
+		return "", err
+	}
+	if len(data) < secretSaltSize {
+		return "", errors.New("encrypted secret is too short")
+	}
+	salt := data[:secretSaltSize]
+	gcm, err := newSecretCipher(passphrase, salt)
+	if err != nil {
+		return "", err
+	}
+	data = data[secretSaltSize:]
+	if len(data) < gcm.NonceSize() {
+		return "", errors.New("encrypted secret is too short")
+	}
+	plain, err := gcm.Open(nil, data[:gcm.NonceSize()], data[gcm.NonceSize():], nil)
+	if err != nil {
+		return "", errors.New("failed to decrypt secret: wrong passphrase?")
+	}
+	return string(plain), nil
+}
+
+func newSecretCipher(passphrase string, salt []byte) (cipher.AEAD, error) {
+	key := secretKey(passphrase, salt, secretKeyIterations)
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// secretKey derives the AES-256 key of the passphrase.
+func secretKey(passphrase string, salt []byte, iterations int) []byte {
+	return pbkdf2.Key([]byte(passphrase), salt, iterations, 32, sha256.New)
+}
+
+// runSecretCommand runs the command with the shell and returns its stdout
+// without the trailing newline, e.g. "pass show vcd/admin".
+func runSecretCommand(command string) (string, error) {
+	var cmd *exec.Cmd
+	if runtime.GOOS == "windows" {
+		cmd = exec.Command("cmd", "/C", command)
+	} else {
+		cmd = exec.Command("sh", "-c", command)
+	}
+	var stdout bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = os.Stderr
+	cmd.Stdin = os.Stdin
+	if err := cmd.Run(); err != nil {
+		return "", fmt.Errorf("command '%s' failed: %w", command, err)
+	}
+	return strings.TrimRight(stdout.String(), "\r\n"), nil
+}
+
+// getSecret returns the secret from the environment variable, the command,
+// or the encrypted store, in this order.
+func (s *Site) getSecret(kind string, stored string, env string, command string) (string, error) {
+	switch {
+	case env != "":
+		secret, ok := os.LookupEnv(env)
+		if !ok {
+			return "", fmt.Errorf("environment variable %s for the %s of site '%s' is not set", env, kind, s.Name)
+		}
+		return secret, nil
+	case command != "":
+		return runSecretCommand(command)
+	case isEncryptedSecret(stored):
+		passphrase, err := getPassphrase(false)
+		if err != nil {
+			return "", err
+		}
+		secret, err := decryptSecret(stored, passphrase)
+		if err != nil {
+			return "", fmt.Errorf("invalid %s of site '%s': %w", kind, s.Name, err)
+		}
+		return secret, nil
+	default:
+		// base64 written by older versions
+		secret, err := base64.StdEncoding.DecodeString(stored)
+		if err != nil {
+			return "", fmt.Errorf("invalid %s of site '%s': %w", kind, s.Name, err)
+		}
+		return string(secret), nil
+	}
+}
+
+func encryptSiteSecret(secret string) (string, error) {
+	stored := config.encryptedSecret()
+	passphrase, err := getPassphrase(stored == "")
+	if err != nil {
+		return "", err
+	}
+	// all the secrets are encrypted with the same passphrase, a typo must not
+	// encrypt this one with another key
+	if stored != "" && !secretPassphraseChecked {
+		if _, err := decryptSecret(stored, passphrase); err != nil {
+			secretPassphrase = ""
+			return "", errors.New("the passphrase does not match the one of the stored passwords")
+		}
+		secretPassphraseChecked = true
+	}
+	return encryptSecret(secret, passphrase)
+}
+
+// encryptedSecret returns one of the encrypted secrets of the sites, or ""
+// when the passphrase is not set yet.
+func (c *Config) encryptedSecret() string {
+	for _, s := range c.Sites {
+		if isEncryptedSecret(s.Password) {
+			return s.Password
+		}
+		if isEncryptedSecret(s.ApiToken) {
+			return s.ApiToken
+		}
+	}
+	return ""
+}
+
+// migratePasswords encrypts the base64 passwords written by older versions.
+// It reports whether any site was migrated.
+func (c *Config) migratePasswords() (bool, error) {
+	migrated := false
+	for i, s := range c.Sites {
+		if s.Password == "" || isEncryptedSecret(s.Password) {
+			continue
+		}
+		password, err := base64.StdEncoding.DecodeString(s.Password)
+		if err != nil {
+			return migrated, fmt.Errorf("invalid password of site '%s': %w", s.Name, err)
+		}
+		if err := c.Sites[i].SetPassword(string(password)); err != nil {
+			return migrated, err
+		}
+		migrated = true
+	}
+	return migrated, nil
+}
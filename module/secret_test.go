@@ -0,0 +1,100 @@
+package module
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"testing"
+)
+
+func TestSecretKey(t *testing.T) {
+	// the PBKDF2-HMAC-SHA256 vectors of the RFC 6070 inputs
+	tests := []struct {
+		passphrase string
+		salt       string
+		iterations int
+		want       string
+	}{
+		{"password", "salt", 1, "120fb6cffcf8b32c43e7225256c4f837a86548c92ccc35480805987cb70be17b"},
+		{"password", "salt", 2, "ae4d0c95af6b46d32d0adff928f06dd02a303f8ef3c251dfd6e2d85a95474c43"},
+		{"password", "salt", 4096, "c5e478d59288c841aa530db6845c4c8d962893a001ce4e11a4963873aa98134a"},
+	}
+	for _, tt := range tests {
+		if got := hex.EncodeToString(secretKey(tt.passphrase, []byte(tt.salt), tt.iterations)); got != tt.want {
+			t.Errorf("secretKey(%q, %q, %d) = %s, want %s", tt.passphrase, tt.salt, tt.iterations, got, tt.want)
+		}
+	}
+}
+
+func TestEncryptSecret(t *testing.T) {
+	tests := []string{"", "secret", "p@ss w0rd \"'<>&", "日本語"}
+	for _, plain := range tests {
+		encrypted, err := encryptSecret(plain, "passphrase")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !isEncryptedSecret(encrypted) {
+			t.Errorf("encryptSecret(%q) = %q, want the %s prefix", plain, encrypted, encryptedSecretPrefix)
+		}
+		got, err := decryptSecret(encrypted, "passphrase")
+		if err != nil {
+			t.Errorf("decryptSecret(encryptSecret(%q)): %v", plain, err)
+			continue
+		}
+		if got != plain {
+			t.Errorf("decryptSecret(encryptSecret(%q)) = %q", plain, got)
+		}
+		if got, err := decryptSecret(encrypted, "Passphrase"); err == nil {
+			t.Errorf("decryptSecret(%q) with a wrong passphrase = %q, want an error", plain, got)
+		}
+	}
+
+	for _, value := range []string{encryptedSecretPrefix, encryptedSecretPrefix + "AAAA", encryptedSecretPrefix + "!"} {
+		if _, err := decryptSecret(value, "passphrase"); err == nil {
+			t.Errorf("decryptSecret(%q) succeeded, want an error", value)
+		}
+	}
+}
+
+func TestMigratePasswords(t *testing.T) {
+	t.Setenv("VCDCTL_PASSPHRASE", "passphrase")
+	t.Cleanup(func() {
+		config = Config{}
+		secretPassphrase, secretPassphraseChecked = "", false
+	})
+	config = Config{Sites: []Site{
+		{Name: "a", Password: base64.StdEncoding.EncodeToString([]byte("secret-a"))},
+		{Name: "b", Password: base64.StdEncoding.EncodeToString([]byte("secret-b"))},
+		{Name: "c", PasswordEnv: "VCDCTL_TEST_PASSWORD"},
+	}}
+
+	migrated, err := config.migratePasswords()
+	if err != nil || !migrated {
+		t.Fatalf("migratePasswords() = %v, %v, want true", migrated, err)
+	}
+	encrypted := []string{}
+	for i, want := range []string{"secret-a", "secret-b"} {
+		s := config.Sites[i]
+		if !isEncryptedSecret(s.Password) {
+			t.Fatalf("password of site %s = %q, want it encrypted", s.Name, s.Password)
+		}
+		got, err := s.GetPassword()
+		if err != nil || got != want {
+			t.Errorf("GetPassword() of site %s = %q, %v, want %q", s.Name, got, err, want)
+		}
+		encrypted = append(encrypted, s.Password)
+	}
+	if config.Sites[2].Password != "" {
+		t.Errorf("password of site c = %q, want it left empty", config.Sites[2].Password)
+	}
+
+	// the encrypted passwords are not migrated again
+	migrated, err = config.migratePasswords()
+	if err != nil || migrated {
+		t.Fatalf("migratePasswords() again = %v, %v, want false", migrated, err)
+	}
+	for i := range encrypted {
+		if config.Sites[i].Password != encrypted[i] {
+			t.Errorf("password of site %s was rewritten", config.Sites[i].Name)
+		}
+	}
+}
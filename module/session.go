@@ -0,0 +1,157 @@
+package module
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+// vCD drops a session after 30 minutes of inactivity by default. The cached
+// token is not used after this, it is extended each time the session is used.
+var sessionIdleTimeout = 30 * time.Minute
+
+// Session is a cached access token of a site.
+type Session struct {
+	Endpoint  string    `json:"endpoint"`
+	User      string    `json:"user"`
+	Token     string    `json:"token"`
+	ExpiresAt time.Time `json:"expiresAt"`
+}
+
+// sessionCacheFilePath returns the path of the session cache, next to the
+// config file (vcdctl.json -> vcdctl.sessions.json).
+func sessionCacheFilePath() string {
+	if configFilePath == "" {
+		configFilePath = defaultConfigFilePath()
+	}
+	return strings.TrimSuffix(configFilePath, filepath.Ext(configFilePath)) + ".sessions.json"
+}
+
+func loadSessions() (map[string]Session, error) {
+	sessions := map[string]Session{}
+	data, err := os.ReadFile(sessionCacheFilePath())
+	if errors.Is(err, os.ErrNotExist) {
+		return sessions, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &sessions); err != nil {
+		// a broken cache only costs a login
+		return map[string]Session{}, nil
+	}
+	return sessions, nil
+}
+
+func saveSessions(sessions map[string]Session) error {
+	data, err := json.MarshalIndent(sessions, "", "  ")
+	if err != nil {
+		return err
+	}
+	path := sessionCacheFilePath()
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return err
+	}
+	if err := os.WriteFile(path, data, 0600); err != nil {
+		return err
+	}
+	// WriteFile keeps the mode of an existing file
+	return os.Chmod(path, 0600)
+}
+
+// cachedToken returns the cached token of the site, or "" when there is no
+// usable one.
+func cachedToken(site Site) string {
+	if replayDir != "" {
+		return ""
+	}
+	sessions, err := loadSessions()
+	if err != nil {
+		return ""
+	}
+	session, ok := sessions[site.Name]
+	if !ok || session.Endpoint != site.Endpoint || session.User != site.User {
+		return ""
+	}
+	if time.Now().After(session.ExpiresAt) {
+		return ""
+	}
+	return session.Token
+}
+
+// cacheToken stores the token of the site with a new expiry. Failing to
+// write the cache is not an error of the command itself.
+func cacheToken(site Site, token string) {
+	if replayDir != "" {
+		return
+	}
+	sessions, err := loadSessions()
+	if err != nil {
+		Log(fmt.Sprintf("failed to load session cache: %v", err))
+		return
+	}
+	sessions[site.Name] = Session{
+		Endpoint:  site.Endpoint,
+		User:      site.User,
+		Token:     token,
+		ExpiresAt: time.Now().Add(sessionIdleTimeout),
+	}
+	if err := saveSessions(sessions); err != nil {
+		Log(fmt.Sprintf("failed to save session cache: %v", err))
+	}
+}
+
+func forgetToken(site Site) error {
+	sessions, err := loadSessions()
+	if err != nil {
+		return err
+	}
+	if _, ok := sessions[site.Name]; !ok {
+		return nil
+	}
+	delete(sessions, site.Name)
+	return saveSessions(sessions)
+}
+
+func NewCmdLogout() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "logout",
+		Short: "delete the cached session of the current site",
+		Args:  cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			if err := initConfig(); err != nil {
+				Fatal(err)
+			}
+			site, err := config.GetCurrentSite()
+			if err != nil {
+				Fatal(err)
+			}
+
+			token := cachedToken(site)
+			if token != "" {
+				vcdClient, err := newVcdClient(site)
+				if err != nil {
+					Fatal(err)
+				}
+				vcdClient.token = token
+				if err := vcdClient.Logout(); err != nil {
+					// the session may have expired already, the cache is cleared anyway
+					var apiError *ApiError
+					if !errors.As(err, &apiError) || apiError.ExitCode() != ExitAuth {
+						Fatal(err)
+					}
+				}
+			}
+			if err := forgetToken(site); err != nil {
+				Fatal(err)
+			}
+		},
+	}
+	return cmd
+}
@@ -22,6 +22,7 @@ func NewCmdSet() *cobra.Command {
 		NewCmdSetPower(),
 		NewCmdSetVAppLease(),
 	)
+	addWaitFlags(cmd)
 	return cmd
 }
 
@@ -42,6 +43,10 @@ func NewCmdSetOrgVdcNetwork() *cobra.Command {
 				return
 			}
 			networkName := args[0]
+			orgvdcName = orgVdcOrContext(orgvdcName)
+			if orgvdcName == "" {
+				Fatal("org vdc name not specified")
+			}
 
 			vdc, err := GetVdc(orgvdcName)
 			if err != nil {
@@ -54,7 +59,7 @@ func NewCmdSetOrgVdcNetwork() *cobra.Command {
 			}
 
 			if connected {
-				edge, err := GetEdge(edgeName, orgvdcName)
+				edge, err := GetEdge(edgeOrContext(edgeName), orgvdcName)
 				if err != nil {
 					Fatal(err)
 				}
@@ -79,22 +84,27 @@ func NewCmdSetOrgVdcNetwork() *cobra.Command {
 			}
 
 			header := map[string]string{"Content-Type": "application/json"}
-			client.Request("PUT", fmt.Sprintf("/cloudapi/1.0.0/orgVdcNetworks/%s", network.Urn), header, data)
+			res, err := client.Request("PUT", fmt.Sprintf("/cloudapi/1.0.0/orgVdcNetworks/%s", network.Urn), header, data)
+			if err != nil {
+				Fatal(err)
+			}
+			if err := waitResponseTask(res); err != nil {
+				Fatal(err)
+			}
 		},
 	}
-	cmd.PersistentFlags().StringVarP(&orgvdcName, "orgvdc", "", "", "org vdc name (required)")
+	cmd.PersistentFlags().StringVarP(&orgvdcName, "orgvdc", "", "", "org vdc name (default: the org vdc of the context)")
 	cmd.PersistentFlags().BoolVarP(&connected, "connected", "", true, "connect network to edge (default true)")
-	cmd.PersistentFlags().StringVarP(&edgeName, "edge", "", "", "edge name (required if connected is true)")
+	cmd.PersistentFlags().StringVarP(&edgeName, "edge", "", "", "edge name (required if connected is true, default: the edge of the context)")
 	cmd.PersistentFlags().BoolVarP(&distributed, "distributed", "", false, "enable distributed connection (default false)")
-	cmd.MarkFlagRequired("orgvdc")
 
 	cmd.RegisterFlagCompletionFunc("orgvdc", func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
 		initClient()
-		return GetOvdcNames(), cobra.ShellCompDirectiveNoFileComp
+		return completeNames(GetOvdcNames())
 	})
 	cmd.RegisterFlagCompletionFunc("edge", func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
 		initClient()
-		return GetEdgeNames(orgvdcName), cobra.ShellCompDirectiveNoFileComp
+		return completeNames(GetEdgeNames(orgVdcOrContext(orgvdcName)))
 	})
 	return cmd
 }
@@ -124,7 +134,7 @@ func NewCmdSetPowerOn() *cobra.Command {
 				return nil, cobra.ShellCompDirectiveNoFileComp
 			}
 			initClient()
-			return GetVAppNames(), cobra.ShellCompDirectiveNoFileComp
+			return completeNames(GetVAppNames())
 		},
 		Run: func(cmd *cobra.Command, args []string) {
 			if len(args) == 0 {
@@ -136,7 +146,13 @@ func NewCmdSetPowerOn() *cobra.Command {
 			if err != nil {
 				Fatal(err)
 			}
-			client.Request("POST", fmt.Sprintf("/api/vApp/%s/power/action/powerOn", vapp.Id), nil, nil)
+			res, err := client.Request("POST", fmt.Sprintf("/api/vApp/%s/power/action/powerOn", vapp.Id), nil, nil)
+			if err != nil {
+				Fatal(err)
+			}
+			if err := waitResponseTask(res); err != nil {
+				Fatal(err)
+			}
 		},
 	}
 	return cmd
@@ -154,7 +170,7 @@ func NewCmdSetVAppLease() *cobra.Command {
 				return nil, cobra.ShellCompDirectiveNoFileComp
 			}
 			initClient()
-			return GetVAppNames(), cobra.ShellCompDirectiveNoFileComp
+			return completeNames(GetVAppNames())
 		},
 		Run: func(cmd *cobra.Command, args []string) {
 			if len(args) != 1 {
@@ -165,7 +181,10 @@ func NewCmdSetVAppLease() *cobra.Command {
 			if err != nil {
 				Fatal(err)
 			}
-			vappLease := GetVAppLease(vapp.Id)
+			vappLease, err := GetVAppLease(vapp.Id)
+			if err != nil {
+				Fatal(err)
+			}
 			newVappLease := LeaseSettingsSectionUpdate{
 				Xmlns:vappLease.Xmlns,
 				XmlnsVmext:vappLease.XmlnsVmext,
@@ -189,7 +208,13 @@ func NewCmdSetVAppLease() *cobra.Command {
 				Fatal(err)
 			}
 			header := map[string]string{"Content-Type": "application/vnd.vmware.vcloud.leaseSettingsSection+xml"}
-			client.Request("PUT", fmt.Sprintf("/api/vApp/%s/leaseSettingsSection/", vapp.Id), header, data)
+			res, err := client.Request("PUT", fmt.Sprintf("/api/vApp/%s/leaseSettingsSection/", vapp.Id), header, data)
+			if err != nil {
+				Fatal(err)
+			}
+			if err := waitResponseTask(res); err != nil {
+				Fatal(err)
+			}
 		},
 	}
 	cmd.PersistentFlags().StringVarP(&leaseTime, "leasetime", "", "86400", "lease time to extend in second (default is 86400)")
@@ -7,9 +7,9 @@ type OrgList struct {
 }
 
 type Org struct {
-	Id   string
-	Href string `xml:"href,attr"`
-	Name string `xml:"name,attr"`
+	Id   string `json:"id"`
+	Href string `xml:"href,attr" json:"href"`
+	Name string `xml:"name,attr" json:"name"`
 }
 
 type OrgJson struct {
@@ -26,17 +26,17 @@ type OrgVdcList struct {
 }
 
 type OrgVdc struct {
-	Name                  string `xml:"name,attr"`
-	Href                  string `xml:"href,attr"`
-	Id                    string
-	IsEnabled             string `xml:"isEnabled,attr"`
-	OrgName               string `xml:"orgName,attr"`
-	ProviderVdcName       string `xml:"providerVdcName,attr"`
-	VcName                string `xml:"vcName,attr"`
-	NetworkProviderType   string `xml:"networkProviderType,attr"`
-	NumberOfVApps         int    `xml:"numberOfVApps,attr"`
-	NumberOfVMs           int    `xml:"numberOfVMs,attr"`
-	NumberOfVAppTemplates int    `xml:"numberOfVAppTemplates,attr"`
+	Name                  string `xml:"name,attr" json:"name"`
+	Href                  string `xml:"href,attr" json:"href"`
+	Id                    string `json:"id"`
+	IsEnabled             string `xml:"isEnabled,attr" json:"isEnabled"`
+	OrgName               string `xml:"orgName,attr" json:"orgName"`
+	ProviderVdcName       string `xml:"providerVdcName,attr" json:"providerVdcName"`
+	VcName                string `xml:"vcName,attr" json:"vcName"`
+	NetworkProviderType   string `xml:"networkProviderType,attr" json:"networkProviderType"`
+	NumberOfVApps         int    `xml:"numberOfVApps,attr" json:"numberOfVApps"`
+	NumberOfVMs           int    `xml:"numberOfVMs,attr" json:"numberOfVMs"`
+	NumberOfVAppTemplates int    `xml:"numberOfVAppTemplates,attr" json:"numberOfVAppTemplates"`
 }
 
 type CapacityWithUsageType struct {
@@ -81,25 +81,21 @@ type Subnets struct {
 	Values []Subnet `json:"values"`
 }
 
-type VAppList struct {
-	Page     int    `xml:"page,attr"`
-	PageSize int    `xml:"pageSize,attr"`
-	Total    int    `xml:"total,attr"`
-	VApp     []VApp `xml:"VAppRecord"`
-}
 
 type VApp struct {
-	Name           string `xml:"name,attr"`
-	Href           string `xml:"href,attr"`
-	Id             string
-	IsEnabled      string `xml:"isEnabled,attr"`
-	Status         string `xml:"status,attr"`
-	OrgHref        string `xml:"org,attr"`
-	OrgName        string
-	VdcName        string `xml:"vdcName,attr"`
-	NumberOfVMs    int    `xml:"numberOfVMs,attr"`
-	TaskStatusName string `xml:"taskStatusName,attr"`
-	TaskStatus     string `xml:"taskStatus,attr"`
+	Name           string `xml:"name,attr" json:"name"`
+	Href           string `xml:"href,attr" json:"href"`
+	Id             string `json:"id"`
+	IsEnabled      string `xml:"isEnabled,attr" json:"isEnabled"`
+	Status         string `xml:"status,attr" json:"status"`
+	OrgHref        string `xml:"org,attr" json:"orgHref"`
+	OrgName        string `json:"orgName"`
+	VdcName        string `xml:"vdcName,attr" json:"vdcName"`
+	NumberOfVMs    int    `xml:"numberOfVMs,attr" json:"numberOfVMs"`
+	TaskStatusName string `xml:"taskStatusName,attr" json:"taskStatusName"`
+	TaskStatus     string `xml:"taskStatus,attr" json:"taskStatus"`
+	// set by get vapp from the lease settings of a running vApp
+	LeaseExpiration string `xml:"-" json:"leaseExpiration,omitempty"`
 }
 
 type OrgVdcNetworkList struct {
@@ -116,9 +112,9 @@ type OrgVdcAvailableNetwork struct {
 }
 
 type Reference struct {
-	Name string `xml:"name,attr"`
-	Href string `xml:"href,attr"`
-	Id   string `xml:"id,attr"`
+	Name string `xml:"name,attr" json:"name"`
+	Href string `xml:"href,attr" json:"href"`
+	Id   string `xml:"id,attr" json:"id"`
 }
 
 type ReferenceJson struct {
@@ -127,12 +123,12 @@ type ReferenceJson struct {
 }
 
 type OrgVdcNetwork struct {
-	Name          string               `xml:"name,attr"`
-	Href          string               `xml:"href,attr"`
-	Urn           string               `xml:"id,attr"`
-	Configuration NetworkConfiguration `xml:"Configuration"`
-	IsShared      string               `xml:"IsShared"`
-	Id            string
+	Name          string               `xml:"name,attr" json:"name"`
+	Href          string               `xml:"href,attr" json:"href"`
+	Urn           string               `xml:"id,attr" json:"urn"`
+	Configuration NetworkConfiguration `xml:"Configuration" json:"configuration"`
+	IsShared      string               `xml:"IsShared" json:"isShared"`
+	Id            string               `json:"id"`
 }
 
 type NetworkConfigSection struct {
@@ -140,18 +136,18 @@ type NetworkConfigSection struct {
 }
 
 type Network struct {
-	Name          string               `xml:"networkName,attr"`
-	Configuration NetworkConfiguration `xml:"Configuration"`
+	Name          string               `xml:"networkName,attr" json:"name"`
+	Configuration NetworkConfiguration `xml:"Configuration" json:"configuration"`
 }
 
 type NetworkConfiguration struct {
-	IpScopes             IpScopeList `xml:"IpScopes"`
-	ParentNetwork        *Reference  `xml:"ParentNetwork,omitempty"`
-	FenceMode            string      `xml:"FenceMode"`
-	DistributedInterface string      `xml:"DistributedInterface,omitempty"`
-	ServiceInterface     string      `xml:"ServiceInterface,omitempty"`
-	GuestVlanAllowed     string      `xml:"GuestVlanAllowed,omitempty"`
-	Connected            string      `xml:"Connected,omitempty"`
+	IpScopes             IpScopeList `xml:"IpScopes" json:"ipScopes"`
+	ParentNetwork        *Reference  `xml:"ParentNetwork,omitempty" json:"parentNetwork,omitempty"`
+	FenceMode            string      `xml:"FenceMode" json:"fenceMode"`
+	DistributedInterface string      `xml:"DistributedInterface,omitempty" json:"distributedInterface,omitempty"`
+	ServiceInterface     string      `xml:"ServiceInterface,omitempty" json:"serviceInterface,omitempty"`
+	GuestVlanAllowed     string      `xml:"GuestVlanAllowed,omitempty" json:"guestVlanAllowed,omitempty"`
+	Connected            string      `xml:"Connected,omitempty" json:"connected,omitempty"`
 }
 
 type OrgVdcNetworkJson struct {
@@ -175,25 +171,27 @@ type OrgVdcNetworkJson struct {
 }
 
 type IpScopeList struct {
-	IpScope []IpScope `xml:"IpScope"`
+	IpScope []IpScope `xml:"IpScope" json:"ipScope"`
 }
 
 type IpScope struct {
-	IsInherited        string `xml:"IsInherited"`
-	Gateway            string `xml:"Gateway"`
-	Netmask            string `xml:"Netmask"`
-	SubnetPrefixLength string `xml:"SubnetPrefixLength"`
-	Dns1               string `xml:"Dns1,omitempty"`
-	Dns2               string `xml:"Dns2,omitempty"`
-	DnsSuffix          string `xml:"DnsSuffix,omitempty"`
-	IsEnabled          string `xml:"IsEnabled"`
+	IsInherited        string `xml:"IsInherited" json:"isInherited"`
+	Gateway            string `xml:"Gateway" json:"gateway"`
+	Netmask            string `xml:"Netmask" json:"netmask"`
+	SubnetPrefixLength string `xml:"SubnetPrefixLength" json:"subnetPrefixLength"`
+	Dns1               string `xml:"Dns1,omitempty" json:"dns1,omitempty"`
+	Dns2               string `xml:"Dns2,omitempty" json:"dns2,omitempty"`
+	DnsSuffix          string `xml:"DnsSuffix,omitempty" json:"dnsSuffix,omitempty"`
+	IsEnabled          string `xml:"IsEnabled" json:"isEnabled"`
 }
 
 type NetworkBacking struct {
-	Name            string        `json:"name"`
-	Id              string        `json:"backingId"`
-	BackingType     string        `json:"backingType"`
-	NetworkProvider ReferenceJson `json:"networkProvider"`
+	Name        string `json:"name"`
+	Id          string `json:"backingId"`
+	BackingType string `json:"backingType"`
+	// replaces BackingType in the newer api versions
+	BackingTypeValue string        `json:"backingTypeValue"`
+	NetworkProvider  ReferenceJson `json:"networkProvider"`
 }
 
 type NetworkBackings struct {
@@ -322,27 +320,27 @@ type VmList struct {
 }
 
 type VM struct {
-	Name                     string                   `xml:"name,attr"`
-	Urn                      string                   `xml:"id,attr"`
-	Href                     string                   `xml:"href,attr"`
-	NetworkConnectionSection NetworkConnectionSection `xml:"NetworkConnectionSection"`
+	Name                     string                   `xml:"name,attr" json:"name"`
+	Urn                      string                   `xml:"id,attr" json:"urn"`
+	Href                     string                   `xml:"href,attr" json:"href"`
+	NetworkConnectionSection NetworkConnectionSection `xml:"NetworkConnectionSection" json:"networkConnectionSection"`
 }
 
 type NetworkConnectionSection struct {
-	PrimaryNetworkConnectionIndex int                 `xml:"PrimaryNetworkConnectionIndex"`
-	NetworkConnection             []NetworkConnection `xml:"NetworkConnection"`
+	PrimaryNetworkConnectionIndex int                 `xml:"PrimaryNetworkConnectionIndex" json:"primaryNetworkConnectionIndex"`
+	NetworkConnection             []NetworkConnection `xml:"NetworkConnection" json:"networkConnection"`
 }
 
 type NetworkConnection struct {
-	Name                             string `xml:"network,attr"`
-	NetworkConnectionIndex           int    `xml:"NetworkConnectionIndex"`
-	IpAddress                        string `xml:"IpAddress"`
-	IpType                           string `xml:"IpType"`
-	IsConnected                      string `xml:"IsConnected"`
-	MACAddress                       string `xml:"MACAddress"`
-	IpAddressAllocationMode          string `xml:"IpAddressAllocationMode"`
-	SecondaryIpAddressAllocationMode string `xml:"SecondaryIpAddressAllocationMode"`
-	NetworkAdapterType               string `xml:"NetworkAdapterType"`
+	Name                             string `xml:"network,attr" json:"name"`
+	NetworkConnectionIndex           int    `xml:"NetworkConnectionIndex" json:"networkConnectionIndex"`
+	IpAddress                        string `xml:"IpAddress" json:"ipAddress"`
+	IpType                           string `xml:"IpType" json:"ipType"`
+	IsConnected                      string `xml:"IsConnected" json:"isConnected"`
+	MACAddress                       string `xml:"MACAddress" json:"macAddress"`
+	IpAddressAllocationMode          string `xml:"IpAddressAllocationMode" json:"ipAddressAllocationMode"`
+	SecondaryIpAddressAllocationMode string `xml:"SecondaryIpAddressAllocationMode" json:"secondaryIpAddressAllocationMode"`
+	NetworkAdapterType               string `xml:"NetworkAdapterType" json:"networkAdapterType"`
 }
 
 type TaskList struct {
@@ -350,44 +348,75 @@ type TaskList struct {
 }
 
 type Task struct {
-	Operation     string      `xml:"operation,attr"`
-	OperationName string      `xml:"operationName,attr"`
-	Status        string      `xml:"status,attr"`
-	StartTime     string      `xml:"startTime,attr"`
-	EndTime       string      `xml:"endTime,attr"`
-	Href          string      `xml:"href,attr"`
-	Urn           string      `xml:"id,attr"`
-	Org           Reference   `xml:"Organization"`
-	User          Reference   `xml:"User"`
-	Owner         Reference   `xml:"Owner"`
-	Error         *TaskError  `xml:"Error,omitempty"`
-	VcTaskList    *VcTaskList `xml:"VcTaskList,omitempty"`
+	Operation     string      `xml:"operation,attr" json:"operation"`
+	OperationName string      `xml:"operationName,attr" json:"operationName"`
+	Status        string      `xml:"status,attr" json:"status"`
+	Progress      int         `xml:"Progress" json:"progress"`
+	StartTime     string      `xml:"startTime,attr" json:"startTime"`
+	EndTime       string      `xml:"endTime,attr" json:"endTime"`
+	Href          string      `xml:"href,attr" json:"href"`
+	Urn           string      `xml:"id,attr" json:"urn"`
+	Org           Reference   `xml:"Organization" json:"org"`
+	User          Reference   `xml:"User" json:"user"`
+	Owner         Reference   `xml:"Owner" json:"owner"`
+	Error         *TaskError  `xml:"Error,omitempty" json:"error,omitempty"`
+	VcTaskList    *VcTaskList `xml:"VcTaskList,omitempty" json:"vcTaskList,omitempty"`
+}
+
+// TaskRecord is a task of the query service.
+type TaskRecord struct {
+	Name          string `xml:"name,attr"`
+	OperationFull string `xml:"operationFull,attr"`
+	Status        string `xml:"status,attr"`
+	StartDate     string `xml:"startDate,attr"`
+	EndDate       string `xml:"endDate,attr"`
+	Href          string `xml:"href,attr"`
+	Org           string `xml:"org,attr"`
+	OrgName       string `xml:"orgName,attr"`
+	Object        string `xml:"object,attr"`
+	ObjectName    string `xml:"objectName,attr"`
+	OwnerName     string `xml:"ownerName,attr"`
+}
+
+// Task converts the record to a task, without the details of GetTask.
+func (r TaskRecord) Task() Task {
+	return Task{
+		Operation:     r.OperationFull,
+		OperationName: r.Name,
+		Status:        r.Status,
+		StartTime:     r.StartDate,
+		EndTime:       r.EndDate,
+		Href:          r.Href,
+		Org:           Reference{Name: r.OrgName, Href: r.Org},
+		User:          Reference{Name: r.OwnerName},
+		Owner:         Reference{Name: r.ObjectName, Href: r.Object},
+	}
 }
 
 type TaskError struct {
-	StackTrace     string          `xml:"stackTrace,attr"`
-	MajorErrorCode string          `xml:"majorErrorCode,attr"`
-	MinorErrorCode string          `xml:"minorErrorCode,attr"`
-	TenantError    TaskTenantError `xml:"TenantError"`
+	StackTrace     string          `xml:"stackTrace,attr" json:"stackTrace"`
+	MajorErrorCode string          `xml:"majorErrorCode,attr" json:"majorErrorCode"`
+	MinorErrorCode string          `xml:"minorErrorCode,attr" json:"minorErrorCode"`
+	TenantError    TaskTenantError `xml:"TenantError" json:"tenantError"`
 }
 
 type TaskTenantError struct {
-	Message        string `xml:"message,attr"`
-	MajorErrorCode string `xml:"majorErrorCode,attr"`
-	MinorErrorCode string `xml:"minorErrorCode,attr"`
+	Message        string `xml:"message,attr" json:"message"`
+	MajorErrorCode string `xml:"majorErrorCode,attr" json:"majorErrorCode"`
+	MinorErrorCode string `xml:"minorErrorCode,attr" json:"minorErrorCode"`
 }
 
 type VcTaskList struct {
-	VcTask []VcTask `xml:"VcTask"`
+	VcTask []VcTask `xml:"VcTask" json:"vcTask"`
 }
 
 type VcTask struct {
-	Name        string `xml:"name,attr"`
-	Description string `xml:"description,attr"`
-	Status      string `xml:"status,attr"`
-	ObjectType  string `xml:"objectType,attr"`
-	ObjectName  string `xml:"objectName,attr"`
-	ObjectMoref string `xml:"objectMoref,attr"`
-	StartTime   string `xml:"startTime,attr"`
-	EndTime     string `xml:"endTime,attr"`
+	Name        string `xml:"name,attr" json:"name"`
+	Description string `xml:"description,attr" json:"description"`
+	Status      string `xml:"status,attr" json:"status"`
+	ObjectType  string `xml:"objectType,attr" json:"objectType"`
+	ObjectName  string `xml:"objectName,attr" json:"objectName"`
+	ObjectMoref string `xml:"objectMoref,attr" json:"objectMoref"`
+	StartTime   string `xml:"startTime,attr" json:"startTime"`
+	EndTime     string `xml:"endTime,attr" json:"endTime"`
 }
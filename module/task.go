@@ -0,0 +1,282 @@
+package module
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	waitTask         bool
+	waitTimeout      time.Duration
+	taskPollInterval = 2 * time.Second
+)
+
+// TaskFailedError is returned when a task ends with error or is aborted.
+type TaskFailedError struct {
+	Task Task
+}
+
+func (e *TaskFailedError) Error() string {
+	msg := e.Task.Status
+	if e.Task.Error != nil {
+		msg = e.Task.Error.TenantError.Message
+		if msg == "" {
+			msg = e.Task.Error.MinorErrorCode
+		}
+	}
+	return fmt.Sprintf("task %s (%s) %s: %s", e.Task.Operation, LastOne(e.Task.Href, "/"), e.Task.Status, msg)
+}
+
+func addWaitFlags(cmd *cobra.Command) {
+	cmd.PersistentFlags().BoolVarP(&waitTask, "wait", "w", false, "wait for the task to complete")
+	cmd.PersistentFlags().DurationVar(&waitTimeout, "timeout", 30*time.Minute, "timeout for --wait")
+}
+
+// waitResponseTask waits for the task started by the request when --wait is
+// specified.
+func waitResponseTask(res *Response) error {
+	if !waitTask {
+		return nil
+	}
+	taskId := TaskIdFromResponse(res)
+	if taskId == "" {
+		// nothing to wait for, the operation was synchronous
+		return nil
+	}
+	_, err := WaitTask(taskId, waitTimeout)
+	return err
+}
+
+// TaskIdFromResponse returns the id of the task started by the request.
+// vCD returns the task in the body (the Task itself, or in the Tasks of the
+// created entity) or in the Location header.
+func TaskIdFromResponse(res *Response) string {
+	decoder := xml.NewDecoder(bytes.NewReader(res.Body))
+	for {
+		token, err := decoder.Token()
+		if err != nil {
+			break
+		}
+		if start, ok := token.(xml.StartElement); ok && start.Name.Local == "Task" {
+			var task Task
+			if err := decoder.DecodeElement(&task, &start); err == nil && task.Href != "" {
+				return LastOne(task.Href, "/")
+			}
+		}
+	}
+
+	if location := http.Header(res.Header).Get("Location"); location != "" {
+		if id := LastOne(location, "/api/task/"); id != location {
+			return id
+		}
+	}
+	return ""
+}
+
+// WaitTask polls the task until it finishes. It returns TaskFailedError when
+// the task ends with error.
+func WaitTask(taskId string, timeout time.Duration) (Task, error) {
+	deadline := time.Now().Add(timeout)
+	lastProgress := ""
+	for {
+		task, err := GetTask(taskId)
+		if err != nil {
+			return task, err
+		}
+
+		progress := fmt.Sprintf("task %s (%s): %s %d%%", task.Operation, taskId, task.Status, task.Progress)
+		if progress != lastProgress {
+			fmt.Fprintln(os.Stderr, progress)
+			lastProgress = progress
+		}
+
+		switch task.Status {
+		case "success":
+			return task, nil
+		case "error", "aborted", "canceled":
+			return task, &TaskFailedError{Task: task}
+		}
+
+		if time.Now().After(deadline) {
+			return task, fmt.Errorf("timed out waiting for task %s after %s", taskId, timeout)
+		}
+		time.Sleep(taskPollInterval)
+	}
+}
+
+func NewCmdTask() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "task",
+		Short: "wait, cancel or follow tasks",
+		PersistentPreRun: func(cmd *cobra.Command, args []string) {
+			initClient()
+		},
+	}
+	cmd.AddCommand(
+		NewCmdTaskWait(),
+		NewCmdTaskCancel(),
+		NewCmdTaskFollow(),
+	)
+	return cmd
+}
+
+func NewCmdTaskWait() *cobra.Command {
+	var timeout time.Duration
+
+	cmd := &cobra.Command{
+		Use:   "wait ${TASK_ID}",
+		Short: "Wait for the task to complete",
+		Args:  cobra.ExactArgs(1),
+		ValidArgsFunction: func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+			if len(args) != 0 {
+				return nil, cobra.ShellCompDirectiveNoFileComp
+			}
+			initClient()
+			return completeNames(GetRunningTaskIds())
+		},
+		Run: func(cmd *cobra.Command, args []string) {
+			task, err := WaitTask(args[0], timeout)
+			if err != nil {
+				Fatal(err)
+			}
+			fmt.Println("Status: " + task.Status)
+			fmt.Println("Time: " + task.StartTime + " - " + task.EndTime)
+		},
+	}
+	cmd.Flags().DurationVar(&timeout, "timeout", 30*time.Minute, "timeout")
+	return cmd
+}
+
+func NewCmdTaskCancel() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "cancel ${TASK_ID}",
+		Short: "Cancel the running task",
+		Args:  cobra.ExactArgs(1),
+		ValidArgsFunction: func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+			if len(args) != 0 {
+				return nil, cobra.ShellCompDirectiveNoFileComp
+			}
+			initClient()
+			return completeNames(GetRunningTaskIds())
+		},
+		Run: func(cmd *cobra.Command, args []string) {
+			if err := CancelTask(args[0]); err != nil {
+				Fatal(err)
+			}
+		},
+	}
+	return cmd
+}
+
+func NewCmdTaskFollow() *cobra.Command {
+	var interval time.Duration
+	var tail int
+
+	cmd := &cobra.Command{
+		Use:   "follow [${ORG_NAME}]",
+		Short: "Show tasks of the org as they start and finish, like tail -f",
+		Args:  cobra.MaximumNArgs(1),
+		ValidArgsFunction: func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+			if len(args) != 0 {
+				return nil, cobra.ShellCompDirectiveNoFileComp
+			}
+			initClient()
+			return completeNames(GetOrgNames())
+		},
+		Run: func(cmd *cobra.Command, args []string) {
+			orgName := ""
+			if len(args) > 0 {
+				orgName = args[0]
+			}
+			orgName = orgOrContext(orgName)
+			if orgName == "" {
+				Fatal("org name not specified")
+			}
+			org, err := GetOrg(orgName)
+			if err != nil {
+				Fatal(err)
+			}
+
+			// status of the tasks already shown, to print only the changes
+			shown := map[string]string{}
+			first := true
+			for {
+				tasks, err := GetTasks(org.Id)
+				if err != nil {
+					Fatal(err)
+				}
+				if first {
+					// mark the old tasks as shown, except the last ones
+					for i, task := range tasks {
+						if i >= tail {
+							shown[task.Href] = task.Status
+						}
+					}
+					first = false
+				}
+				// tasks are sorted from the newest, print from the oldest
+				for i := len(tasks) - 1; i >= 0; i-- {
+					task := tasks[i]
+					if status, ok := shown[task.Href]; ok && status == task.Status {
+						continue
+					}
+					shown[task.Href] = task.Status
+					fmt.Printf("%s  %-10s  %-40s  %s  %s  %s\n",
+						task.StartTime,
+						task.Status,
+						task.Operation,
+						LastOne(task.Href, "/"),
+						task.Owner.Name,
+						task.User.Name)
+				}
+				// forget the tasks out of the polled window, they do not come back
+				polled := map[string]bool{}
+				for _, task := range tasks {
+					polled[task.Href] = true
+				}
+				for href := range shown {
+					if !polled[href] {
+						delete(shown, href)
+					}
+				}
+				time.Sleep(interval)
+			}
+		},
+	}
+	cmd.Flags().DurationVar(&interval, "interval", 5*time.Second, "polling interval")
+	cmd.Flags().IntVar(&tail, "tail", 5, "number of recent tasks to show first")
+	return cmd
+}
+
+// CancelTask requests vCD to cancel the running task.
+func CancelTask(taskId string) error {
+	_, err := client.Request("POST", fmt.Sprintf("/api/task/%s/action/cancel", taskId), nil, nil)
+	return err
+}
+
+// GetRunningTaskIds returns the ids of the tasks of the default org which
+// are not finished yet.
+func GetRunningTaskIds() ([]string, error) {
+	org, err := GetOrg(orgOrContext(""))
+	if err != nil {
+		return nil, err
+	}
+	tasks, err := GetTasks(org.Id)
+	if err != nil {
+		return nil, err
+	}
+	ids := []string{}
+	for _, task := range tasks {
+		switch task.Status {
+		case "queued", "preRunning", "running":
+			ids = append(ids, LastOne(task.Href, "/"))
+		}
+	}
+	return ids, nil
+}
@@ -0,0 +1,190 @@
+package module
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+// newTLSConfig returns the tls config for the site. The server certificate
+// is verified by default, against the system roots and the caFile of the
+// site. When a fingerprint is pinned, only the certificate with the
+// fingerprint is accepted instead.
+func newTLSConfig(site Site) (*tls.Config, error) {
+	tlsConfig := &tls.Config{}
+
+	if site.CaFile != "" {
+		pool, err := x509.SystemCertPool()
+		if err != nil {
+			pool = x509.NewCertPool()
+		}
+		pemData, err := os.ReadFile(resolveConfigPath(site.CaFile))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read ca file of site '%s': %w", site.Name, err)
+		}
+		if !pool.AppendCertsFromPEM(pemData) {
+			return nil, fmt.Errorf("no certificate found in ca file '%s' of site '%s'", site.CaFile, site.Name)
+		}
+		tlsConfig.RootCAs = pool
+	}
+
+	if site.Fingerprint != "" {
+		pinned := normalizeFingerprint(site.Fingerprint)
+		tlsConfig.InsecureSkipVerify = true
+		tlsConfig.VerifyPeerCertificate = func(rawCerts [][]byte, _ [][]*x509.Certificate) error {
+			if len(rawCerts) == 0 {
+				return errors.New("no server certificate")
+			}
+			if fingerprint := certFingerprint(rawCerts[0]); fingerprint != pinned {
+				return fmt.Errorf("server certificate fingerprint %s does not match the pinned fingerprint of site '%s'", formatFingerprint(fingerprint), site.Name)
+			}
+			return nil
+		}
+	}
+
+	if site.Insecure {
+		tlsConfig.InsecureSkipVerify = true
+		tlsConfig.VerifyPeerCertificate = nil
+	}
+	return tlsConfig, nil
+}
+
+// resolveConfigPath makes a path relative to the directory of the config file.
+func resolveConfigPath(path string) string {
+	if filepath.IsAbs(path) {
+		return path
+	}
+	return filepath.Join(filepath.Dir(configFilePath), path)
+}
+
+// certFingerprint returns the SHA-256 fingerprint of the DER certificate as
+// lowercase hex.
+func certFingerprint(der []byte) string {
+	sum := sha256.Sum256(der)
+	return hex.EncodeToString(sum[:])
+}
+
+// normalizeFingerprint accepts "AB:CD:..." as well as "abcd...".
+func normalizeFingerprint(fingerprint string) string {
+	fingerprint = strings.TrimPrefix(strings.ToLower(fingerprint), "sha256:")
+	return strings.NewReplacer(":", "", " ", "").Replace(fingerprint)
+}
+
+// formatFingerprint formats the fingerprint as "AB:CD:...".
+func formatFingerprint(fingerprint string) string {
+	pairs := []string{}
+	for i := 0; i+1 < len(fingerprint); i += 2 {
+		pairs = append(pairs, strings.ToUpper(fingerprint[i:i+2]))
+	}
+	return strings.Join(pairs, ":")
+}
+
+// fetchCertificates returns the certificate chain presented by the endpoint,
+// without verifying it.
+func fetchCertificates(endpoint string) ([]*x509.Certificate, error) {
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return nil, err
+	}
+	host := u.Host
+	if u.Port() == "" {
+		host = net.JoinHostPort(u.Hostname(), "443")
+	}
+	dialer := &net.Dialer{Timeout: 30 * time.Second}
+	conn, err := tls.DialWithDialer(dialer, "tcp", host, &tls.Config{InsecureSkipVerify: true})
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	return conn.ConnectionState().PeerCertificates, nil
+}
+
+func NewCmdConfigTrustSite() *cobra.Command {
+	var yes bool
+	var pin bool
+
+	cmd := &cobra.Command{
+		Use:   "trust-site ${SITE_NAME}",
+		Short: "fetch the certificate chain of the site and trust it",
+		Long: `Fetch the certificate chain of the site and save it next to the config file
+as the ca file of the site, after showing the fingerprint for confirmation.
+With --pin, the fingerprint of the server certificate is pinned instead.`,
+		Args: cobra.ExactArgs(1),
+		ValidArgsFunction: func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+			if len(args) != 0 {
+				return nil, cobra.ShellCompDirectiveNoFileComp
+			}
+			return completeSiteNames()
+		},
+		Run: func(cmd *cobra.Command, args []string) {
+			site, err := config.GetSite(args[0])
+			if err != nil {
+				Fatal(err)
+			}
+
+			certs, err := fetchCertificates(site.Endpoint)
+			if err != nil {
+				Fatal(err)
+			}
+			if len(certs) == 0 {
+				Fatal(fmt.Sprintf("%s presented no certificate", site.Endpoint))
+			}
+			for i, cert := range certs {
+				fmt.Printf("[%d] Subject: %s\n", i, cert.Subject)
+				fmt.Printf("    Issuer: %s\n", cert.Issuer)
+				fmt.Printf("    Validity: %s - %s\n", cert.NotBefore.Format(time.RFC3339), cert.NotAfter.Format(time.RFC3339))
+				fmt.Printf("    SHA-256 Fingerprint: %s\n", formatFingerprint(certFingerprint(cert.Raw)))
+			}
+
+			if !yes && !confirm(fmt.Sprintf("Trust the certificate of %s?", site.Endpoint)) {
+				Fatal("canceled")
+			}
+
+			if pin {
+				site.Fingerprint = certFingerprint(certs[0].Raw)
+			} else {
+				path := strings.TrimSuffix(configFilePath, filepath.Ext(configFilePath)) + "." + site.Name + ".pem"
+				var data []byte
+				for _, cert := range certs {
+					data = append(data, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: cert.Raw})...)
+				}
+				if err := os.WriteFile(path, data, 0600); err != nil {
+					Fatal(err)
+				}
+				site.CaFile = path
+				fmt.Println("saved certificate chain to " + path)
+			}
+			site.Insecure = false
+			config.UpdateSite(site)
+
+			if err := saveConfig(); err != nil {
+				Fatal(err)
+			}
+		},
+	}
+	cmd.Flags().BoolVarP(&yes, "yes", "y", false, "trust without confirmation")
+	cmd.Flags().BoolVar(&pin, "pin", false, "pin the fingerprint of the server certificate instead of saving the chain")
+
+	return cmd
+}
+
+// confirm asks the question on the terminal and reports whether the answer is yes.
+func confirm(question string) bool {
+	fmt.Fprintf(os.Stderr, "%s [y/N]: ", question)
+	answer, _ := bufio.NewReader(os.Stdin).ReadString('\n')
+	answer = strings.ToLower(strings.TrimSpace(answer))
+	return answer == "y" || answer == "yes"
+}
@@ -0,0 +1,133 @@
+package module
+
+import (
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestNormalizeFingerprint(t *testing.T) {
+	tests := []struct {
+		fingerprint string
+		want        string
+	}{
+		{"ab:cd:ef:01", "abcdef01"},
+		{"AB:CD:EF:01", "abcdef01"},
+		{"ABCDEF01", "abcdef01"},
+		{"sha256:AB CD EF 01", "abcdef01"},
+		{"SHA256:ab:cd:ef:01", "abcdef01"},
+	}
+	for _, tt := range tests {
+		if got := normalizeFingerprint(tt.fingerprint); got != tt.want {
+			t.Errorf("normalizeFingerprint(%q) = %q, want %q", tt.fingerprint, got, tt.want)
+		}
+	}
+	if got := formatFingerprint("abcdef01"); got != "AB:CD:EF:01" {
+		t.Errorf("formatFingerprint(abcdef01) = %q", got)
+	}
+}
+
+// writeCaFile writes the certificate of the server as a pem file.
+func writeCaFile(t *testing.T, srv *httptest.Server) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "ca.pem")
+	data := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: srv.Certificate().Raw})
+	if err := os.WriteFile(path, data, 0600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestNewTLSConfig(t *testing.T) {
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer srv.Close()
+	caFile := writeCaFile(t, srv)
+	fingerprint := formatFingerprint(certFingerprint(srv.Certificate().Raw))
+	other := strings.Repeat("00", 32)
+
+	tests := []struct {
+		name string
+		site Site
+		want string
+	}{
+		{"system roots", Site{}, "certificate signed by unknown authority"},
+		{"ca file", Site{CaFile: caFile}, ""},
+		{"pinned", Site{Fingerprint: fingerprint}, ""},
+		{"pinned lowercase", Site{Fingerprint: strings.ToLower(strings.ReplaceAll(fingerprint, ":", ""))}, ""},
+		{"pin mismatch", Site{Fingerprint: other}, "does not match the pinned fingerprint"},
+		{"pin mismatch with ca file", Site{CaFile: caFile, Fingerprint: other}, "does not match the pinned fingerprint"},
+		{"insecure", Site{Insecure: true}, ""},
+	}
+	for _, tt := range tests {
+		tt.site.Name = "lab"
+		tlsConfig, err := newTLSConfig(tt.site)
+		if err != nil {
+			t.Errorf("%s: newTLSConfig: %v", tt.name, err)
+			continue
+		}
+		c := &http.Client{Transport: &http.Transport{TLSClientConfig: tlsConfig}}
+		res, err := c.Get(srv.URL)
+		if err == nil {
+			res.Body.Close()
+		}
+		switch {
+		case tt.want == "" && err != nil:
+			t.Errorf("%s: %v", tt.name, err)
+		case tt.want != "" && (err == nil || !strings.Contains(err.Error(), tt.want)):
+			t.Errorf("%s: err = %v, want %q", tt.name, err, tt.want)
+		}
+	}
+
+	empty := filepath.Join(t.TempDir(), "empty.pem")
+	os.WriteFile(empty, []byte("no certificate"), 0600)
+	for _, path := range []string{filepath.Join(t.TempDir(), "missing.pem"), empty} {
+		if _, err := newTLSConfig(Site{Name: "lab", CaFile: path}); err == nil {
+			t.Errorf("ca file %s: want an error", path)
+		}
+	}
+}
+
+func TestConfigTrustSite(t *testing.T) {
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer srv.Close()
+	configFilePath = filepath.Join(t.TempDir(), "vcdctl.json")
+	config = Config{CurrentSite: "lab", Sites: []Site{{Name: "lab", Endpoint: srv.URL, Insecure: true}}}
+	t.Cleanup(func() {
+		configFilePath, config = "", Config{}
+	})
+
+	cmd := NewCmdConfigTrustSite()
+	cmd.SetArgs([]string{"lab", "--yes"})
+	captureStdout(t, func() {
+		if err := cmd.Execute(); err != nil {
+			t.Fatal(err)
+		}
+	})
+
+	site, _ := config.GetSite("lab")
+	if site.Insecure || site.CaFile == "" {
+		t.Fatalf("site = %+v, want the ca file set and insecure cleared", site)
+	}
+	info, err := os.Stat(site.CaFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode().Perm() != 0600 {
+		t.Errorf("mode of %s = %v, want 0600", site.CaFile, info.Mode().Perm())
+	}
+	// the saved chain verifies the server
+	tlsConfig, err := newTLSConfig(site)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := &http.Client{Transport: &http.Transport{TLSClientConfig: tlsConfig}}
+	res, err := c.Get(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	res.Body.Close()
+}
@@ -5,6 +5,8 @@ import (
 	"os"
 	"strings"
 	"text/tabwriter"
+
+	"github.com/spf13/cobra"
 )
 
 func PrityPrint(header []string, value [][]string) {
@@ -24,9 +26,34 @@ func LastOne(str string, spliter string) string {
 	return ""
 }
 
+// Fatal prints the error and exits. The exit code depends on the kind of
+// the error (see exitCode).
 func Fatal(v ...any) {
-	fmt.Printf("error: %v\n", v...)
-	os.Exit(1)
+	fmt.Fprintf(os.Stderr, "error: %v\n", v...)
+	if len(v) == 1 {
+		if err, ok := v[0].(error); ok {
+			os.Exit(exitCode(err))
+		}
+	}
+	os.Exit(ExitError)
+}
+
+// completeNames converts the result of Get*Names for ValidArgsFunction.
+func completeNames(names []string, err error) ([]string, cobra.ShellCompDirective) {
+	if err != nil {
+		return nil, cobra.ShellCompDirectiveError
+	}
+	return names, cobra.ShellCompDirectiveNoFileComp
+}
+
+func countNonEmpty(values ...string) int {
+	count := 0
+	for _, v := range values {
+		if v != "" {
+			count++
+		}
+	}
+	return count
 }
 
 func min(a, b int) int {
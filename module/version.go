@@ -0,0 +1,120 @@
+package module
+
+import (
+	"encoding/xml"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Range of the API versions vcdctl is written for. The highest version
+// supported by both vcdctl and the server is used.
+const (
+	minApiVersion = "33.0"
+	maxApiVersion = "39.0"
+)
+
+// API versions required by newer features.
+const (
+	apiVersionVrfLiteUplink = "36.0"
+	apiVersionIpSpace       = "37.1"
+)
+
+// set by --api-version
+var apiVersionOverride string
+
+type SupportedVersions struct {
+	VersionInfo []struct {
+		Deprecated bool   `xml:"deprecated,attr"`
+		Version    string `xml:"Version"`
+	} `xml:"VersionInfo"`
+}
+
+// NegotiateApiVersion queries /api/versions (no login needed) and returns
+// the highest version supported by both vcdctl and the server.
+func (c *VcdClient) NegotiateApiVersion() (string, error) {
+	header := map[string]string{"Accept": "application/*+xml"}
+	res, err := c.Request("GET", "/api/versions", header, nil)
+	if err != nil {
+		return "", err
+	}
+	var versions SupportedVersions
+	if err := xml.Unmarshal(res.Body, &versions); err != nil {
+		return "", responseError(res, err)
+	}
+
+	best := ""
+	for _, v := range versions.VersionInfo {
+		if v.Deprecated {
+			continue
+		}
+		if compareApiVersion(v.Version, minApiVersion) < 0 || compareApiVersion(v.Version, maxApiVersion) > 0 {
+			continue
+		}
+		if best == "" || compareApiVersion(v.Version, best) > 0 {
+			best = v.Version
+		}
+	}
+	if best == "" {
+		return "", fmt.Errorf("%s supports no API version between %s and %s", c.site.Endpoint, minApiVersion, maxApiVersion)
+	}
+	return best, nil
+}
+
+// prepareApiVersion sets the API version of the client: --api-version, the
+// version stored on the site, or the negotiated one, which is then stored.
+func (c *VcdClient) prepareApiVersion() error {
+	if apiVersionOverride != "" {
+		c.site.ApiVersion = apiVersionOverride
+		return nil
+	}
+	if c.site.ApiVersion != "" {
+		return nil
+	}
+	version, err := c.NegotiateApiVersion()
+	if err != nil {
+		return err
+	}
+	c.site.ApiVersion = version
+	if replayDir != "" {
+		// a replay does not change the config
+		return nil
+	}
+	if saved, err := config.GetSite(c.site.Name); err == nil {
+		saved.ApiVersion = version
+		config.UpdateSite(saved)
+		return saveConfig()
+	}
+	return nil
+}
+
+// compareApiVersion compares versions like "37.1" numerically.
+func compareApiVersion(a, b string) int {
+	as := strings.Split(a, ".")
+	bs := strings.Split(b, ".")
+	for i := 0; i < len(as) || i < len(bs); i++ {
+		var x, y int
+		if i < len(as) {
+			x, _ = strconv.Atoi(as[i])
+		}
+		if i < len(bs) {
+			y, _ = strconv.Atoi(bs[i])
+		}
+		if x != y {
+			if x < y {
+				return -1
+			}
+			return 1
+		}
+	}
+	return 0
+}
+
+// RequireApiVersion fails when the API version in use is older than the
+// version the feature needs.
+func RequireApiVersion(version string, feature string) error {
+	if compareApiVersion(client.site.ApiVersion, version) < 0 {
+		return fmt.Errorf("%s requires API version %s or later, but site '%s' uses %s", feature, version, client.site.Name, client.site.ApiVersion)
+	}
+	return nil
+}
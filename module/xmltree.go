@@ -0,0 +1,429 @@
+package module
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// xmlNode is an element of a response, with the names as written in the
+// document (e.g. "ovf:Info", "xmlns:vmext"), so that printing it again keeps
+// the prefixes.
+type xmlNode struct {
+	Name     string
+	Attrs    []xml.Attr
+	Children []*xmlNode
+	Text     string
+}
+
+// xmlDocument is a parsed response: the xml declaration, if any, and the
+// root element.
+type xmlDocument struct {
+	Declaration string
+	Root        *xmlNode
+}
+
+func qualifiedName(name xml.Name) string {
+	if name.Space == "" {
+		return name.Local
+	}
+	return name.Space + ":" + name.Local
+}
+
+func localName(name string) string {
+	if i := strings.LastIndex(name, ":"); i >= 0 {
+		return name[i+1:]
+	}
+	return name
+}
+
+func parseXmlDocument(body []byte) (*xmlDocument, error) {
+	doc := &xmlDocument{}
+	decoder := xml.NewDecoder(bytes.NewReader(body))
+	stack := []*xmlNode{}
+	for {
+		// RawToken keeps the prefixes instead of resolving the namespaces
+		token, err := decoder.RawToken()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		switch t := token.(type) {
+		case xml.ProcInst:
+			if t.Target == "xml" && doc.Root == nil {
+				doc.Declaration = "<?xml " + string(t.Inst) + "?>"
+			}
+		case xml.StartElement:
+			node := &xmlNode{Name: qualifiedName(t.Name)}
+			for _, attr := range t.Attr {
+				attr.Name = xml.Name{Local: qualifiedName(attr.Name)}
+				node.Attrs = append(node.Attrs, attr)
+			}
+			if len(stack) == 0 {
+				if doc.Root != nil {
+					return nil, errors.New("xml: more than one root element")
+				}
+				doc.Root = node
+			} else {
+				parent := stack[len(stack)-1]
+				parent.Children = append(parent.Children, node)
+			}
+			stack = append(stack, node)
+		case xml.EndElement:
+			if len(stack) == 0 || stack[len(stack)-1].Name != qualifiedName(t.Name) {
+				return nil, fmt.Errorf("xml: unexpected end element </%s>", qualifiedName(t.Name))
+			}
+			stack = stack[:len(stack)-1]
+		case xml.CharData:
+			if len(stack) > 0 {
+				node := stack[len(stack)-1]
+				node.Text += string(t)
+			}
+		}
+	}
+	if doc.Root == nil {
+		return nil, errors.New("xml: no root element")
+	}
+	if len(stack) > 0 {
+		return nil, fmt.Errorf("xml: element <%s> is not closed", stack[len(stack)-1].Name)
+	}
+	doc.Root.dropIndentation()
+	return doc, nil
+}
+
+// dropIndentation drops the whitespace between the elements. The text of
+// the leaf elements is kept as is, e.g. the lines of a customization script.
+func (n *xmlNode) dropIndentation() {
+	if len(n.Children) == 0 {
+		return
+	}
+	n.Text = strings.TrimSpace(n.Text)
+	for _, c := range n.Children {
+		c.dropIndentation()
+	}
+}
+
+func (n *xmlNode) attr(name string) (string, bool) {
+	for _, attr := range n.Attrs {
+		if attr.Name.Local == name || (!strings.Contains(name, ":") && localName(attr.Name.Local) == name && !strings.HasPrefix(attr.Name.Local, "xmlns")) {
+			return attr.Value, true
+		}
+	}
+	return "", false
+}
+
+func (d *xmlDocument) String() string {
+	var b strings.Builder
+	if d.Declaration != "" {
+		b.WriteString(d.Declaration + "\n")
+	}
+	d.Root.write(&b, "")
+	return strings.TrimSuffix(b.String(), "\n")
+}
+
+func (n *xmlNode) String() string {
+	var b strings.Builder
+	n.write(&b, "")
+	return strings.TrimSuffix(b.String(), "\n")
+}
+
+// Only what must be is escaped, unlike xml.EscapeText which escapes the
+// quotes and the newlines of the text as well. The whitespace of the
+// attributes is escaped, a parser would normalize it to spaces.
+var (
+	xmlTextEscaper = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;")
+	xmlAttrEscaper = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;", `"`, "&quot;", "\n", "&#xA;", "\r", "&#xD;", "\t", "&#x9;")
+)
+
+func (n *xmlNode) write(b *strings.Builder, indent string) {
+	b.WriteString(indent + "<" + n.Name)
+	for _, attr := range n.Attrs {
+		b.WriteString(" " + attr.Name.Local + `="` + xmlAttrEscaper.Replace(attr.Value) + `"`)
+	}
+	switch {
+	case len(n.Children) == 0 && n.Text == "":
+		b.WriteString("/>\n")
+	case len(n.Children) == 0:
+		b.WriteString(">" + xmlTextEscaper.Replace(n.Text) + "</" + n.Name + ">\n")
+	default:
+		b.WriteString(">\n")
+		if n.Text != "" {
+			b.WriteString(indent + "  " + xmlTextEscaper.Replace(n.Text) + "\n")
+		}
+		for _, c := range n.Children {
+			c.write(b, indent+"  ")
+		}
+		b.WriteString(indent + "</" + n.Name + ">\n")
+	}
+}
+
+// ToJSON converts the document to json values: {"Root": {...}}. An element
+// is an object of its attributes ("@name", the namespace declarations as
+// "@xmlns:prefix"), its child elements and its text ("#text"). An element
+// with text only is the string. The names keep their prefix (e.g.
+// "ovf:Info").
+//
+// The child elements are always arrays, even when there is one: the shape
+// does not depend on the number of records, e.g.
+// {.QueryResultRecords.VAppRecord[*].@name} or {.VApp.Children[0].Vm[*].@name}.
+func (d *xmlDocument) ToJSON() map[string]any {
+	return map[string]any{d.Root.Name: d.Root.toJSON()}
+}
+
+func (n *xmlNode) toJSON() any {
+	if len(n.Attrs) == 0 && len(n.Children) == 0 {
+		return n.Text
+	}
+	m := map[string]any{}
+	for _, attr := range n.Attrs {
+		m["@"+attr.Name.Local] = attr.Value
+	}
+	for _, c := range n.Children {
+		existing, _ := m[c.Name].([]any)
+		m[c.Name] = append(existing, c.toJSON())
+	}
+	if n.Text != "" {
+		m["#text"] = n.Text
+	}
+	return m
+}
+
+// xpathStep is a step of the supported XPath subset:
+//
+//	/a/b  //b  *  @attr  @*  text()  a[2]  a[@attr='x']  a[@attr]  a[b='x']
+type xpathStep struct {
+	descendant bool
+	name       string
+	predicates []string
+}
+
+func parseXPath(expr string) ([]xpathStep, error) {
+	if strings.TrimSpace(expr) == "" {
+		return nil, errors.New("xpath: empty expression")
+	}
+	steps := []xpathStep{}
+	rest := strings.TrimSpace(expr)
+	for rest != "" {
+		step := xpathStep{}
+		if strings.HasPrefix(rest, "//") {
+			step.descendant = true
+			rest = rest[2:]
+		} else {
+			rest = strings.TrimPrefix(rest, "/")
+		}
+		// the name, up to the predicates or the next step
+		end := strings.IndexAny(rest, "[/")
+		if end < 0 {
+			end = len(rest)
+		}
+		step.name = strings.TrimSpace(rest[:end])
+		if step.name == "" {
+			return nil, fmt.Errorf("xpath: empty step in %q", expr)
+		}
+		rest = rest[end:]
+		for strings.HasPrefix(rest, "[") {
+			close := xpathPredicateEnd(rest)
+			if close < 0 {
+				return nil, fmt.Errorf("xpath: unclosed predicate in %q", expr)
+			}
+			step.predicates = append(step.predicates, strings.TrimSpace(rest[1:close]))
+			rest = rest[close+1:]
+		}
+		if rest != "" && !strings.HasPrefix(rest, "/") {
+			return nil, fmt.Errorf("xpath: unexpected %q in %q", rest, expr)
+		}
+		steps = append(steps, step)
+	}
+	for i, step := range steps[:len(steps)-1] {
+		if strings.HasPrefix(step.name, "@") || step.name == "text()" {
+			return nil, fmt.Errorf("xpath: %s must be the last step in %q", steps[i].name, expr)
+		}
+	}
+	return steps, nil
+}
+
+// xpathPredicateEnd returns the index of the ] closing the predicate at the
+// start of s, skipping the quoted strings, or -1.
+func xpathPredicateEnd(s string) int {
+	var quote byte
+	for i := 1; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '\'' || c == '"':
+			quote = c
+		case c == ']':
+			return i
+		}
+	}
+	return -1
+}
+
+// SelectXPath evaluates the expression from the document, the first step
+// matching the root element. The results are the elements as xml, or the
+// values of the attributes and texts.
+func (d *xmlDocument) SelectXPath(expr string) ([]string, error) {
+	steps, err := parseXPath(expr)
+	if err != nil {
+		return nil, err
+	}
+	document := &xmlNode{Children: []*xmlNode{d.Root}}
+	current := []*xmlNode{document}
+	for i, step := range steps {
+		last := i == len(steps)-1
+		if last && (strings.HasPrefix(step.name, "@") || step.name == "text()") {
+			return selectValues(current, step), nil
+		}
+		next := []*xmlNode{}
+		seen := map[*xmlNode]bool{}
+		for _, node := range current {
+			parents := []*xmlNode{node}
+			if step.descendant {
+				parents = node.descendantsOrSelf()
+			}
+			for _, parent := range parents {
+				matched, err := matchXPathStep(parent.Children, step)
+				if err != nil {
+					return nil, err
+				}
+				for _, m := range matched {
+					if !seen[m] {
+						seen[m] = true
+						next = append(next, m)
+					}
+				}
+			}
+		}
+		current = next
+	}
+	results := []string{}
+	for _, node := range current {
+		results = append(results, node.String())
+	}
+	return results, nil
+}
+
+func (n *xmlNode) descendantsOrSelf() []*xmlNode {
+	nodes := []*xmlNode{n}
+	for _, c := range n.Children {
+		nodes = append(nodes, c.descendantsOrSelf()...)
+	}
+	return nodes
+}
+
+func selectValues(nodes []*xmlNode, step xpathStep) []string {
+	values := []string{}
+	for _, node := range nodes {
+		targets := []*xmlNode{node}
+		if step.descendant {
+			targets = node.descendantsOrSelf()
+		}
+		for _, t := range targets {
+			switch {
+			case step.name == "text()":
+				if t.Text != "" {
+					values = append(values, t.Text)
+				}
+			case step.name == "@*":
+				for _, attr := range t.Attrs {
+					values = append(values, attr.Value)
+				}
+			default:
+				if value, ok := t.attr(step.name[1:]); ok {
+					values = append(values, value)
+				}
+			}
+		}
+	}
+	return values
+}
+
+func matchXPathStep(children []*xmlNode, step xpathStep) ([]*xmlNode, error) {
+	matched := []*xmlNode{}
+	for _, c := range children {
+		if matchXPathName(c.Name, step.name) {
+			matched = append(matched, c)
+		}
+	}
+	for _, p := range step.predicates {
+		if position, err := strconv.Atoi(p); err == nil {
+			if position < 1 || position > len(matched) {
+				matched = nil
+			} else {
+				matched = []*xmlNode{matched[position-1]}
+			}
+			continue
+		}
+		filtered := []*xmlNode{}
+		for _, m := range matched {
+			ok, err := matchXPathPredicate(m, p)
+			if err != nil {
+				return nil, err
+			}
+			if ok {
+				filtered = append(filtered, m)
+			}
+		}
+		matched = filtered
+	}
+	return matched, nil
+}
+
+// matchXPathName matches "prefix:Local" exactly, and "Local" with any prefix.
+func matchXPathName(name string, test string) bool {
+	if test == "*" {
+		return true
+	}
+	if strings.Contains(test, ":") {
+		return name == test
+	}
+	return localName(name) == test
+}
+
+// matchXPathPredicate evaluates @attr, @attr='v', child, child='v' and text()='v'.
+func matchXPathPredicate(n *xmlNode, predicate string) (bool, error) {
+	left, right, hasValue := strings.Cut(predicate, "=")
+	left = strings.TrimSpace(left)
+	var want string
+	if hasValue {
+		right = strings.TrimSpace(right)
+		if len(right) < 2 || (right[0] != '\'' && right[0] != '"') || right[len(right)-1] != right[0] {
+			return false, fmt.Errorf("xpath: expected a quoted value in [%s]", predicate)
+		}
+		want = right[1 : len(right)-1]
+	}
+
+	values := []string{}
+	switch {
+	case strings.HasPrefix(left, "@"):
+		if value, ok := n.attr(left[1:]); ok {
+			values = append(values, value)
+		}
+	case left == "text()":
+		values = append(values, n.Text)
+	default:
+		for _, c := range n.Children {
+			if matchXPathName(c.Name, left) {
+				values = append(values, c.Text)
+			}
+		}
+	}
+	if !hasValue {
+		return len(values) > 0, nil
+	}
+	for _, value := range values {
+		if value == want {
+			return true, nil
+		}
+	}
+	return false, nil
+}
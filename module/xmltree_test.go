@@ -0,0 +1,209 @@
+package module
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const guestCustomizationXml = `<?xml version="1.0" encoding="UTF-8"?>
+<GuestCustomizationSection xmlns="http://www.vmware.com/vcloud/v1.5" xmlns:ovf="http://schemas.dmtf.org/ovf/envelope/1" href="https://vcd.example.com/api/vApp/vm-1/guestCustomizationSection/" ovf:required="false">
+    <ovf:Info>Specifies Guest OS Customization Settings</ovf:Info>
+    <Enabled>true</Enabled>
+    <AdminPassword/>
+    <CustomizationScript>#!/bin/sh
+  echo "&lt;hi&gt;" &amp;&amp; echo 'it&apos;s'
+	exit 0
+</CustomizationScript>
+    <ComputerName>  web01  </ComputerName>
+    <Link rel="edit" href="https://vcd.example.com/api/vApp/vm-1/guestCustomizationSection/" type="application/vnd.vmware.vcloud.guestCustomizationSection+xml" name="a &quot;b&quot;&#xA;c"/>
+</GuestCustomizationSection>`
+
+func TestXmlDocumentString(t *testing.T) {
+	doc, err := parseXmlDocument([]byte(guestCustomizationXml))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `<?xml version="1.0" encoding="UTF-8"?>
+<GuestCustomizationSection xmlns="http://www.vmware.com/vcloud/v1.5" xmlns:ovf="http://schemas.dmtf.org/ovf/envelope/1" href="https://vcd.example.com/api/vApp/vm-1/guestCustomizationSection/" ovf:required="false">
+  <ovf:Info>Specifies Guest OS Customization Settings</ovf:Info>
+  <Enabled>true</Enabled>
+  <AdminPassword/>
+  <CustomizationScript>#!/bin/sh
+  echo "&lt;hi&gt;" &amp;&amp; echo 'it's'
+	exit 0
+</CustomizationScript>
+  <ComputerName>  web01  </ComputerName>
+  <Link rel="edit" href="https://vcd.example.com/api/vApp/vm-1/guestCustomizationSection/" type="application/vnd.vmware.vcloud.guestCustomizationSection+xml" name="a &quot;b&quot;&#xA;c"/>
+</GuestCustomizationSection>`
+	if got := doc.String(); got != want {
+		t.Errorf("String() =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestXmlDocumentRoundTrip(t *testing.T) {
+	tests := []string{
+		guestCustomizationXml,
+		`<a><b>  leading and trailing  </b><c>line1
+line2</c></a>`,
+		`<a x="1&amp;2" y="&lt;&gt;" z="tab&#x9;end"><b>&amp;&lt;&gt;"'</b><c/><c></c></a>`,
+		`<ns:a xmlns:ns="urn:x"><ns:b ns:c="d">e</ns:b></ns:a>`,
+		`<a><![CDATA[<not> & an element]]></a>`,
+	}
+	for _, body := range tests {
+		doc, err := parseXmlDocument([]byte(body))
+		if err != nil {
+			t.Errorf("parse %q: %v", body, err)
+			continue
+		}
+		again, err := parseXmlDocument([]byte(doc.String()))
+		if err != nil {
+			t.Errorf("parse the printed %q: %v", doc.String(), err)
+			continue
+		}
+		if !reflect.DeepEqual(doc, again) {
+			t.Errorf("round trip of %q changed it:\n%s", body, doc.String())
+		}
+	}
+
+	doc, _ := parseXmlDocument([]byte(guestCustomizationXml))
+	script := doc.Root.Children[3]
+	if want := "#!/bin/sh\n  echo \"<hi>\" && echo 'it's'\n\texit 0\n"; script.Text != want {
+		t.Errorf("CustomizationScript = %q, want %q", script.Text, want)
+	}
+}
+
+func TestParseXmlDocumentErrors(t *testing.T) {
+	for _, body := range []string{"", "text", "<a>", "<a></b>", "<a/><b/>"} {
+		if _, err := parseXmlDocument([]byte(body)); err == nil {
+			t.Errorf("parseXmlDocument(%q) succeeded, want an error", body)
+		}
+	}
+}
+
+func TestXmlDocumentToJSON(t *testing.T) {
+	tests := []struct {
+		body string
+		want string
+	}{
+		// one record or two, the records are an array
+		{
+			`<QueryResultRecords total="1"><VAppRecord name="web01"/></QueryResultRecords>`,
+			`{"QueryResultRecords":{"@total":"1","VAppRecord":[{"@name":"web01"}]}}`,
+		},
+		{
+			`<QueryResultRecords total="2"><VAppRecord name="web01"/><Link rel="next"/><VAppRecord name="web02"/></QueryResultRecords>`,
+			`{"QueryResultRecords":{"@total":"2","Link":[{"@rel":"next"}],"VAppRecord":[{"@name":"web01"},{"@name":"web02"}]}}`,
+		},
+		{
+			`<QueryResultRecords total="0"/>`,
+			`{"QueryResultRecords":{"@total":"0"}}`,
+		},
+		// text only elements are strings, the prefixes and namespaces are kept
+		{
+			`<VApp xmlns="urn:v" xmlns:ovf="urn:ovf" name="a"><ovf:Info>i</ovf:Info><Description/><Text lang="en">t</Text></VApp>`,
+			`{"VApp":{"@name":"a","@xmlns":"urn:v","@xmlns:ovf":"urn:ovf","Description":[""],"Text":[{"#text":"t","@lang":"en"}],"ovf:Info":["i"]}}`,
+		},
+		{
+			`<Script>a
+  b</Script>`,
+			`{"Script":"a\n  b"}`,
+		},
+	}
+	for _, tt := range tests {
+		doc, err := parseXmlDocument([]byte(tt.body))
+		if err != nil {
+			t.Errorf("parse %q: %v", tt.body, err)
+			continue
+		}
+		got, err := json.Marshal(doc.ToJSON())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(got) != tt.want {
+			t.Errorf("ToJSON(%q) =\n%s\nwant\n%s", tt.body, got, tt.want)
+		}
+	}
+}
+
+const vappXml = `<VApp xmlns="http://www.vmware.com/vcloud/v1.5" xmlns:ovf="http://schemas.dmtf.org/ovf/envelope/1" name="web &amp; db" status="4">
+  <Link rel="up" href="https://vcd.example.com/api/vdc/1"/>
+  <Link rel="down" href="https://vcd.example.com/api/vApp/vapp-1/owner"/>
+  <ovf:Info>info</ovf:Info>
+  <Description>d</Description>
+  <Children>
+    <Vm name="vm1" status="4"><Description>first</Description></Vm>
+    <Vm name="web]1" status="8"/>
+    <Vm name="a='b'"/>
+  </Children>
+</VApp>`
+
+func TestSelectXPath(t *testing.T) {
+	doc, err := parseXmlDocument([]byte(vappXml))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		expr string
+		want []string
+	}{
+		{"/VApp/@name", []string{"web & db"}},
+		{"/VApp/@*", []string{"http://www.vmware.com/vcloud/v1.5", "http://schemas.dmtf.org/ovf/envelope/1", "web & db", "4"}},
+		{"/VApp/Link[2]/@href", []string{"https://vcd.example.com/api/vApp/vapp-1/owner"}},
+		{"/VApp/Link[3]", []string{}},
+		{"/VApp/Link[@rel='up']/@href", []string{"https://vcd.example.com/api/vdc/1"}},
+		{`//Link[@rel="down"]/@rel`, []string{"down"}},
+		{"//Vm/@name", []string{"vm1", "web]1", "a='b'"}},
+		{"//Vm[@name='web]1']/@status", []string{"8"}},
+		{`//Vm[@name="a='b'"]/@name`, []string{"a='b'"}},
+		{"//Vm[@status]", []string{`<Vm name="vm1" status="4">
+  <Description>first</Description>
+</Vm>`, `<Vm name="web]1" status="8"/>`}},
+		{"//Vm[Description='first']/@name", []string{"vm1"}},
+		{"//Vm[Description]/@name", []string{"vm1"}},
+		{"//Vm[@status='4'][1]/@name", []string{"vm1"}},
+		{"//Description/text()", []string{"d", "first"}},
+		{"/VApp/*/@rel", []string{"up", "down"}},
+		// with or without the prefix
+		{"/VApp/Info/text()", []string{"info"}},
+		{"/VApp/ovf:Info", []string{"<ovf:Info>info</ovf:Info>"}},
+		{"/VApp/vcloud:Info", []string{}},
+		{"/Nothing", []string{}},
+	}
+	for _, tt := range tests {
+		got, err := doc.SelectXPath(tt.expr)
+		if err != nil {
+			t.Errorf("SelectXPath(%q): %v", tt.expr, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("SelectXPath(%q) = %q, want %q", tt.expr, got, tt.want)
+		}
+	}
+}
+
+func TestSelectXPathErrors(t *testing.T) {
+	doc, err := parseXmlDocument([]byte(vappXml))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		expr string
+		want string
+	}{
+		{"", "empty expression"},
+		{"/VApp//", "empty step"},
+		{"//Vm[@name='x'", "unclosed predicate"},
+		{"//Vm[@name='x]", "unclosed predicate"},
+		{"//Vm[@name=x]", "quoted value"},
+		{"/VApp/@name/x", "must be the last step"},
+		{"/VApp/Link[1]x", "unexpected"},
+	}
+	for _, tt := range tests {
+		_, err := doc.SelectXPath(tt.expr)
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("SelectXPath(%q) error = %v, want %q", tt.expr, err, tt.want)
+		}
+	}
+}